@@ -4,7 +4,7 @@ go 1.13
 
 require (
 	github.com/DATA-DOG/go-sqlmock v1.4.1
-	github.com/aws/aws-sdk-go v1.29.16
+	github.com/aws/aws-sdk-go v1.44.0
 	github.com/cactus/go-statsd-client/statsd v0.0.0-20191106001114-12b4e2b38748
 	github.com/stretchr/testify v1.4.0
 	github.com/uber-go/tally v3.3.15+incompatible
@@ -12,6 +12,5 @@ require (
 	go.uber.org/zap v1.14.0
 	golang.org/x/lint v0.0.0-20200302205851-738671d3881b // indirect
 	golang.org/x/tools v0.0.0-20200304024140-c4206d458c3f // indirect
-	gopkg.in/yaml.v2 v2.2.5 // indirect
 	honnef.co/go/tools v0.0.1-2020.1.3 // indirect
 )
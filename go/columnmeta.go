@@ -0,0 +1,117 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package athenadriver
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/glue"
+	"go.uber.org/zap"
+)
+
+// ColumnMeta is the metadata of a result set column, with its comment in Glue Data Catalog.
+type ColumnMeta struct {
+	Name    string
+	Type    string
+	Comment string
+}
+
+// ColumnMetas is to get the metadata of the columns of the result set. Comments are only fetched from
+// Glue GetTable when ColumnComments is enabled in Config, which needs Glue permissions and one API call
+// per table. The table of a column is the one reported by Athena, or else the first of tables having
+// the column. Each of tables is like `db.table`, or `table` in the database of Config. Comment is empty
+// if the table of a column can't be resolved or got. nil is returned if Rows was closed before its last page.
+func (r *Rows) ColumnMetas(tables ...string) []ColumnMeta {
+	if r.ResultOutput == nil {
+		return nil
+	}
+	columns := r.ResultOutput.ResultSet.ResultSetMetadata.ColumnInfo
+	metas := make([]ColumnMeta, len(columns))
+	for i, colInfo := range columns {
+		metas[i] = ColumnMeta{
+			Name: aws.StringValue(colInfo.Name),
+			Type: aws.StringValue(colInfo.Type),
+		}
+	}
+	if r.glueAPI == nil || !r.config.IsColumnComments() {
+		return metas
+	}
+
+	// comments of a table by column name, nil if the table can't be got
+	comments := make(map[string]map[string]string)
+	getComments := func(db string, table string) map[string]string {
+		if db == "" {
+			db = r.config.GetDB()
+		}
+		key := db + "." + table
+		if c, ok := comments[key]; ok {
+			return c
+		}
+		comments[key] = r.getColumnComments(db, table)
+		return comments[key]
+	}
+	for i, colInfo := range columns {
+		if table := aws.StringValue(colInfo.TableName); table != "" {
+			metas[i].Comment = getComments(aws.StringValue(colInfo.SchemaName), table)[metas[i].Name]
+			continue
+		}
+		for _, t := range tables {
+			db, table := "", t
+			if idx := strings.Index(t, "."); idx >= 0 {
+				db, table = t[:idx], t[idx+1:]
+			}
+			if comment, ok := getComments(db, table)[metas[i].Name]; ok {
+				metas[i].Comment = comment
+				break
+			}
+		}
+	}
+	return metas
+}
+
+// getColumnComments is to get the comments of the columns and partition keys of a table from Glue by
+// column name. nil is returned if the table can't be got.
+func (r *Rows) getColumnComments(db string, table string) map[string]string {
+	out, err := r.glueAPI.GetTableWithContext(r.ctx, &glue.GetTableInput{
+		DatabaseName: aws.String(db),
+		Name:         aws.String(table),
+	})
+	if err != nil || out.Table == nil {
+		r.tracer.Scope().Counter(DriverName + ".failure.rows.gettable").Inc(1)
+		if err != nil {
+			r.tracer.Log(WarnLevel, "failed to get table from Glue",
+				zap.String("queryID", r.queryID),
+				zap.String("table", db+"."+table),
+				zap.String("error", err.Error()))
+		}
+		return nil
+	}
+	comments := make(map[string]string)
+	var columns []*glue.Column
+	if out.Table.StorageDescriptor != nil {
+		columns = out.Table.StorageDescriptor.Columns
+	}
+	for _, c := range append(columns, out.Table.PartitionKeys...) {
+		comments[aws.StringValue(c.Name)] = aws.StringValue(c.Comment)
+	}
+	return comments
+}
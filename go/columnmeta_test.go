@@ -0,0 +1,91 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package athenadriver
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/glue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRows_ColumnMetas(t *testing.T) {
+	c := createConnectionFixture()
+	c.connector.config.SetDB("sampledb")
+	mg := &mockGlueClient{
+		tables: map[string]*glue.TableData{
+			"sampledb.t1": {
+				StorageDescriptor: &glue.StorageDescriptor{
+					Columns: []*glue.Column{newGlueColumn("i", "the id")},
+				},
+				PartitionKeys: []*glue.Column{newGlueColumn("s", "the partition")},
+			},
+			"other.t2": {
+				StorageDescriptor: &glue.StorageDescriptor{
+					Columns: []*glue.Column{newGlueColumn("f", "the factor"), newGlueColumn("i", "not this")},
+				},
+			},
+		},
+	}
+	c.glueAPI = mg
+
+	driverRows, err := c.QueryContext(context.Background(), "SELECT_NULL_MIXED", []driver.NamedValue{})
+	assert.Nil(t, err)
+	rows := driverRows.(*Rows)
+	expected := []ColumnMeta{
+		{Name: "i", Type: "bigint"},
+		{Name: "f", Type: "double"},
+		{Name: "b", Type: "boolean"},
+		{Name: "t", Type: "timestamp"},
+		{Name: "s", Type: "varchar"},
+	}
+	// disabled by default
+	assert.Equal(t, expected, rows.ColumnMetas("t1"))
+	assert.Empty(t, mg.getTableCalls)
+
+	c.connector.config.SetColumnComments(true)
+	expected[0].Comment = "the id"
+	expected[1].Comment = "the factor"
+	expected[4].Comment = "the partition"
+	// each table is got once, and the first table having the column is used
+	assert.Equal(t, expected, rows.ColumnMetas("t1", "other.t2"))
+	assert.Equal(t, []string{"sampledb.t1", "other.t2"}, mg.getTableCalls)
+
+	// the table reported by Athena is used, and missing tables are skipped
+	mg.getTableCalls = nil
+	columns := rows.ResultOutput.ResultSet.ResultSetMetadata.ColumnInfo
+	columns[1].SchemaName = aws.String("other")
+	columns[1].TableName = aws.String("t2")
+	assert.Equal(t, expected, rows.ColumnMetas("missing", "t1"))
+	assert.Equal(t, []string{"sampledb.missing", "sampledb.t1", "other.t2"}, mg.getTableCalls)
+
+	// without Glue
+	rows.glueAPI = nil
+	assert.Equal(t, "", rows.ColumnMetas("t1")[0].Comment)
+
+	// closed before the last page, which drops the result set
+	rows.ResultOutput.NextToken = aws.String("next")
+	assert.Nil(t, rows.Close())
+	assert.Nil(t, rows.ColumnMetas("t1"))
+}
@@ -23,7 +23,12 @@ package athenadriver
 import (
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
 )
 
 // Config is for AWS Athena Driver Config.
@@ -31,6 +36,7 @@ import (
 type Config struct {
 	dsn    url.URL
 	values url.Values
+	logger Logger
 }
 
 var reSecretAccessKey = regexp.MustCompile(`secretAccessKey=[^&]+`)
@@ -112,7 +118,20 @@ func NewConfig(s string) (*Config, error) {
 
 //
 func (c *Config) isValid() bool {
-	return c.dsn.Scheme == "s3" && c.values.Get("region") != ""
+	for _, key := range []string{"DMLQueryTimeout", "DDLQueryTimeout"} {
+		if val := c.values.Get(key); val != "" {
+			if d, err := time.ParseDuration(val); err != nil || d <= 0 {
+				return false
+			}
+		}
+	}
+	if t := c.values.Get("outputPrefixTemplate"); t != "" && !isValidOutputPrefixTemplate(t) {
+		return false
+	}
+	if o := c.values.Get("AclOption"); o != "" && !isValidAclOption(o) {
+		return false
+	}
+	return c.dsn.Scheme == "s3"
 }
 
 // String is to return the string form of DSN.
@@ -166,14 +185,39 @@ func (c *Config) SetRegion(o string) error {
 	return nil
 }
 
-// GetRegion is getter of Region.
+// GetRegion is getter of Region. It will try to get region from:
+//   1. string stored in c.values
+//   2. environmental variable ${AWS_REGION} or ${AWS_DEFAULT_REGION}
+//   3. AWS shared config file(~/.aws/config or ${AWS_CONFIG_FILE}) for profile ${AWS_PROFILE}
+// An empty string is returned if none of them has region.
 func (c *Config) GetRegion() string {
+	if val := c.getRegionFromDSNOrEnv(); val != "" {
+		return val
+	}
+	return getSharedConfigRegion()
+}
+
+// getRegionFromDSNOrEnv is to get region from DSN or environment variables, without reading the AWS shared
+// config file.
+func (c *Config) getRegionFromDSNOrEnv() string {
 	if val := c.values.Get("region"); val != "" {
 		return val
 	}
 	return GetFromEnvVal(regionEnvKeys)
 }
 
+// getSharedConfigRegion is to get region from AWS shared config file, the same way as AWS SDK does
+// when ${AWS_SDK_LOAD_CONFIG} is set.
+func getSharedConfigRegion() string {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return ""
+	}
+	return aws.StringValue(sess.Config.Region)
+}
+
 // SetUser is a setter of User.
 func (c *Config) SetUser(o string) {
 	c.dsn.User = url.UserPassword(o, "")
@@ -271,6 +315,88 @@ func (c *Config) GetOutputBucket() string {
 	return c.dsn.Scheme + "://" + c.dsn.Host + "/" + c.dsn.Path
 }
 
+// SetOutputPrefixTemplate is to set the template of the S3 key prefix appended to OutputBucket for the results
+// of each query, like `results/{yyyy}/{mm}/{dd}/`. Date tokens {yyyy}, {mm}, {dd} and {hh} are expanded in UTC
+// when the query is submitted, so results can be expired by S3 lifecycle rules. Empty template disables it.
+func (c *Config) SetOutputPrefixTemplate(t string) error {
+	if t == "" {
+		c.values.Del("outputPrefixTemplate")
+		return nil
+	}
+	if !isValidOutputPrefixTemplate(t) {
+		return ErrConfigOutputPrefixTemplate
+	}
+	c.values.Set("outputPrefixTemplate", t)
+	return nil
+}
+
+// GetOutputPrefixTemplate is getter of OutputPrefixTemplate.
+func (c *Config) GetOutputPrefixTemplate() string {
+	return c.values.Get("outputPrefixTemplate")
+}
+
+// GetOutputLocation is to get the S3 URI of the results of a query submitted at t, which is OutputBucket
+// with OutputPrefixTemplate expanded and appended.
+func (c *Config) GetOutputLocation(t time.Time) string {
+	location := c.GetOutputBucket()
+	template := c.GetOutputPrefixTemplate()
+	if template == "" {
+		return location
+	}
+	if !strings.HasSuffix(location, "/") {
+		location += "/"
+	}
+	return location + expandOutputPrefix(template, t)
+}
+
+// SetAclOption is to set the S3AclOption of the result configuration of queries, so the owner of a bucket in
+// another account can read the query results. The only allowed value is BUCKET_OWNER_FULL_CONTROL, which is case
+// insensitive. Empty option disables it. It is ignored if the workgroup enforces its own result configuration.
+func (c *Config) SetAclOption(o string) error {
+	if o == "" {
+		c.values.Del("AclOption")
+		return nil
+	}
+	if !isValidAclOption(o) {
+		return ErrConfigAclOption
+	}
+	c.values.Set("AclOption", strings.ToUpper(o))
+	return nil
+}
+
+// GetAclOption is getter of AclOption.
+func (c *Config) GetAclOption() string {
+	return c.values.Get("AclOption")
+}
+
+// SetEngineVersion is to set the Athena engine version queries must run on, like "Athena engine version 3".
+// Athena doesn't take an engine version per query, so it is set on the workgroups created remotely, and queries
+// fail with ErrEngineVersionMismatch in a workgroup whose effective engine version is another one.
+// "AUTO" lets Athena choose, and empty version disables the check.
+func (c *Config) SetEngineVersion(v string) {
+	if v == "" {
+		c.values.Del("EngineVersion")
+		return
+	}
+	c.values.Set("EngineVersion", v)
+}
+
+// GetEngineVersion is getter of EngineVersion.
+func (c *Config) GetEngineVersion() string {
+	return c.values.Get("EngineVersion")
+}
+
+// isValidAclOption is to check if o is one of the S3AclOption values of Athena.
+// https://docs.aws.amazon.com/athena/latest/APIReference/API_AclConfiguration.html
+func isValidAclOption(o string) bool {
+	return strings.EqualFold(o, "BUCKET_OWNER_FULL_CONTROL")
+}
+
+// hasOutputBucket is to check if OutputBucket is set in DSN.
+func (c *Config) hasOutputBucket() bool {
+	return c.dsn.Host != ""
+}
+
 // GetWorkgroup is getter of Workgroup.
 func (c *Config) GetWorkgroup() Workgroup {
 	tagString := c.values.Get("tag")
@@ -305,6 +431,11 @@ func (c *Config) IsMissingAsDefault() bool {
 	return c.values.Get("missingAsDefault") == "true"
 }
 
+// IsMissingAsNil return true if missing value is set to be returned as nil, i.e. SQL NULL.
+func (c *Config) IsMissingAsNil() bool {
+	return c.values.Get("missingAsNil") == "true"
+}
+
 // SetMissingAsEmptyString is to set if missing value is returned as empty string.
 func (c *Config) SetMissingAsEmptyString(b bool) {
 	missingAsEmptyString := "true"
@@ -324,6 +455,17 @@ func (c *Config) SetMissingAsDefault(b bool) {
 
 }
 
+// SetMissingAsNil is to set if missing value is returned as nil, so that it can be scanned into
+// sql.NullString, sql.NullInt64, sql.NullFloat64, sql.NullBool and sql.NullTime as NULL.
+// It only takes effect when both MissingAsEmptyString and MissingAsDefault are false.
+func (c *Config) SetMissingAsNil(b bool) {
+	if b {
+		c.values.Set("missingAsNil", "true")
+	} else {
+		c.values.Set("missingAsNil", "false")
+	}
+}
+
 // CheckColumnMasked is to check if a specific column has been masked by some value.
 // https://stackoverflow.com/questions/30285169/replace-the-empty-or-null-value-with-specific-value-in-hive-query-result/30289503
 func (c *Config) CheckColumnMasked(columnName string) (string, bool) {
@@ -380,7 +522,7 @@ func (c *Config) SetMetrics(b bool) {
 	}
 }
 
-// SetReadOnly is to set if only SELECT/SHOW/DESC are allowed
+// SetReadOnly is to set if only SELECT/SHOW/DESC/EXPLAIN are allowed. Other statements fail with ErrReadOnly.
 func (c *Config) SetReadOnly(b bool) {
 	if b {
 		c.values.Set("ReadOnly", "true")
@@ -389,7 +531,7 @@ func (c *Config) SetReadOnly(b bool) {
 	}
 }
 
-// IsReadOnly is to check if only SELECT/SHOW/DESC are allowed
+// IsReadOnly is to check if only SELECT/SHOW/DESC/EXPLAIN are allowed
 func (c *Config) IsReadOnly() bool {
 	return c.values.Get("ReadOnly") == "true"
 }
@@ -407,3 +549,280 @@ func (c *Config) SetMoneyWise(b bool) {
 func (c *Config) IsMoneyWise() bool {
 	return c.values.Get("MoneyWise") == "true"
 }
+
+// SetAthenaEndpoint is to set a custom Athena endpoint like http://localhost:4566 for LocalStack.
+// The default endpoint resolved by AWS SDK is used if it is empty.
+func (c *Config) SetAthenaEndpoint(endpoint string) {
+	if endpoint == "" {
+		c.values.Del("AthenaEndpoint")
+	} else {
+		c.values.Set("AthenaEndpoint", endpoint)
+	}
+}
+
+// GetAthenaEndpoint is getter of AthenaEndpoint.
+func (c *Config) GetAthenaEndpoint() string {
+	return c.values.Get("AthenaEndpoint")
+}
+
+// SetS3Endpoint is to set a custom S3 endpoint like http://localhost:4566 for LocalStack.
+// The default endpoint resolved by AWS SDK is used if it is empty.
+func (c *Config) SetS3Endpoint(endpoint string) {
+	if endpoint == "" {
+		c.values.Del("S3Endpoint")
+	} else {
+		c.values.Set("S3Endpoint", endpoint)
+	}
+}
+
+// GetS3Endpoint is getter of S3Endpoint.
+func (c *Config) GetS3Endpoint() string {
+	return c.values.Get("S3Endpoint")
+}
+
+// SetS3ForcePathStyle is to set if path style addressing like http://s3.amazonaws.com/BUCKET/KEY is used
+// for S3, which is usually required by S3 compatible services like LocalStack.
+func (c *Config) SetS3ForcePathStyle(b bool) {
+	if b {
+		c.values.Set("S3ForcePathStyle", "true")
+	} else {
+		c.values.Set("S3ForcePathStyle", "false")
+	}
+}
+
+// IsS3ForcePathStyle is to check if path style addressing is used for S3.
+func (c *Config) IsS3ForcePathStyle() bool {
+	return c.values.Get("S3ForcePathStyle") == "true"
+}
+
+// SetCleanupResults is to set if the result file and its metadata file of a query are deleted from S3
+// when its rows are closed. The default is false.
+func (c *Config) SetCleanupResults(b bool) {
+	if b {
+		c.values.Set("CleanupResults", "true")
+	} else {
+		c.values.Set("CleanupResults", "false")
+	}
+}
+
+// IsCleanupResults is to check if query result files are deleted from S3 when rows are closed.
+func (c *Config) IsCleanupResults() bool {
+	return c.values.Get("CleanupResults") == "true"
+}
+
+// SetColumnComments is to set if the comments of result set columns are fetched from Glue Data Catalog
+// by Rows.ColumnMetas(), which needs Glue permissions. The default is false.
+func (c *Config) SetColumnComments(b bool) {
+	if b {
+		c.values.Set("columnComments", "true")
+	} else {
+		c.values.Set("columnComments", "false")
+	}
+}
+
+// IsColumnComments is to check if the comments of result set columns are fetched from Glue.
+func (c *Config) IsColumnComments() bool {
+	return c.values.Get("columnComments") == "true"
+}
+
+// SetJSONAsString is to set if json values are returned as string, like before they were returned as []byte.
+// The default is false. It is for code scanning json into interface{} and asserting .(string).
+func (c *Config) SetJSONAsString(b bool) {
+	if b {
+		c.values.Set("JSONAsString", "true")
+	} else {
+		c.values.Set("JSONAsString", "false")
+	}
+}
+
+// IsJSONAsString is to check if json values are returned as string instead of []byte.
+func (c *Config) IsJSONAsString() bool {
+	return c.values.Get("JSONAsString") == "true"
+}
+
+// SetDMLQueryTimeout is to set the timeout of DML and UTILITY queries. It must be positive.
+func (c *Config) SetDMLQueryTimeout(d time.Duration) error {
+	if d <= 0 {
+		return ErrConfigQueryTimeout
+	}
+	c.values.Set("DMLQueryTimeout", d.String())
+	return nil
+}
+
+// GetDMLQueryTimeout is getter of DMLQueryTimeout. The default is DMLQueryTimeout seconds.
+func (c *Config) GetDMLQueryTimeout() time.Duration {
+	return c.getQueryTimeout("DMLQueryTimeout", DMLQueryTimeout*time.Second)
+}
+
+// SetDDLQueryTimeout is to set the timeout of DDL queries. It must be positive.
+func (c *Config) SetDDLQueryTimeout(d time.Duration) error {
+	if d <= 0 {
+		return ErrConfigQueryTimeout
+	}
+	c.values.Set("DDLQueryTimeout", d.String())
+	return nil
+}
+
+// GetDDLQueryTimeout is getter of DDLQueryTimeout. The default is DDLQueryTimeout seconds.
+func (c *Config) GetDDLQueryTimeout() time.Duration {
+	return c.getQueryTimeout("DDLQueryTimeout", DDLQueryTimeout*time.Second)
+}
+
+func (c *Config) getQueryTimeout(key string, defaultTimeout time.Duration) time.Duration {
+	d, err := time.ParseDuration(c.values.Get(key))
+	if err != nil || d <= 0 {
+		return defaultTimeout
+	}
+	return d
+}
+
+// SetMaxScannedBytes is to set the max bytes a query can scan. A query still running after scanning more than
+// that is stopped with ErrScanLimitExceeded. 0 means unlimited, which is the default.
+func (c *Config) SetMaxScannedBytes(n int64) {
+	if n > 0 {
+		c.values.Set("MaxScannedBytes", strconv.FormatInt(n, 10))
+	} else {
+		c.values.Del("MaxScannedBytes")
+	}
+}
+
+// GetMaxScannedBytes is getter of MaxScannedBytes. 0 is returned if it is not set or invalid.
+func (c *Config) GetMaxScannedBytes() int64 {
+	n, err := strconv.ParseInt(c.values.Get("MaxScannedBytes"), 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// SetOutputAccessDeniedRetries is to set how many times a query failed with S3 access denied on its output
+// location is started again, like when a bucket policy just changed isn't effective in S3 yet. The backoff
+// before each retry starts from OutputAccessDeniedRetryBackoff and doubles. 0 means no retry, which is the default.
+func (c *Config) SetOutputAccessDeniedRetries(n int) {
+	if n > 0 {
+		c.values.Set("OutputAccessDeniedRetries", strconv.Itoa(n))
+	} else {
+		c.values.Del("OutputAccessDeniedRetries")
+	}
+}
+
+// GetOutputAccessDeniedRetries is getter of OutputAccessDeniedRetries. 0 is returned if it is not set or invalid.
+func (c *Config) GetOutputAccessDeniedRetries() int {
+	n, err := strconv.Atoi(c.values.Get("OutputAccessDeniedRetries"))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// SetOutputAccessDeniedRetryBackoff is to set the backoff before the first retry of a query failed with S3
+// access denied on its output location. The default is OutputAccessDeniedRetryBackoff seconds.
+func (c *Config) SetOutputAccessDeniedRetryBackoff(d time.Duration) {
+	if d > 0 {
+		c.values.Set("OutputAccessDeniedRetryBackoff", d.String())
+	} else {
+		c.values.Del("OutputAccessDeniedRetryBackoff")
+	}
+}
+
+// GetOutputAccessDeniedRetryBackoff is getter of OutputAccessDeniedRetryBackoff.
+func (c *Config) GetOutputAccessDeniedRetryBackoff() time.Duration {
+	d, err := time.ParseDuration(c.values.Get("OutputAccessDeniedRetryBackoff"))
+	if err != nil || d <= 0 {
+		return OutputAccessDeniedRetryBackoff * time.Second
+	}
+	return d
+}
+
+// SetPricePerTB is to set the price in USD per TB of data scanned to estimate query cost, as it varies by region.
+// The default is DefaultPricePerTB, the price in us-east-1.
+func (c *Config) SetPricePerTB(price float64) {
+	if price > 0 {
+		c.values.Set("PricePerTB", strconv.FormatFloat(price, 'f', -1, 64))
+	} else {
+		c.values.Del("PricePerTB")
+	}
+}
+
+// GetPricePerTB is getter of PricePerTB. DefaultPricePerTB is returned if it is not set or invalid.
+func (c *Config) GetPricePerTB() float64 {
+	price, err := strconv.ParseFloat(c.values.Get("PricePerTB"), 64)
+	if err != nil || price <= 0 {
+		return DefaultPricePerTB
+	}
+	return price
+}
+
+// SetMaxConcurrentQueries is to set the max number of queries of a sql.DB running in Athena at the same time,
+// like to stay under the concurrent query quota of a workgroup. More queries wait for a slot before being
+// started, or until their context is done. Unlike sql.DB.SetMaxOpenConns, idle connections don't take a slot.
+// 0 means unlimited, which is the default.
+func (c *Config) SetMaxConcurrentQueries(n int) {
+	if n > 0 {
+		c.values.Set("MaxConcurrentQueries", strconv.Itoa(n))
+	} else {
+		c.values.Del("MaxConcurrentQueries")
+	}
+}
+
+// GetMaxConcurrentQueries is getter of MaxConcurrentQueries. 0 is returned if it is not set or invalid.
+func (c *Config) GetMaxConcurrentQueries() int {
+	n, err := strconv.Atoi(c.values.Get("MaxConcurrentQueries"))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// SetMaxQueueWait is to set the max time a query can stay in QUEUED state before it is cancelled with
+// ErrQueueTimeout. 0 means no limit, which is the default.
+func (c *Config) SetMaxQueueWait(d time.Duration) {
+	if d > 0 {
+		c.values.Set("MaxQueueWait", d.String())
+	} else {
+		c.values.Del("MaxQueueWait")
+	}
+}
+
+// GetMaxQueueWait is getter of MaxQueueWait. 0 is returned if it is not set or invalid.
+func (c *Config) GetMaxQueueWait() time.Duration {
+	d, err := time.ParseDuration(c.values.Get("MaxQueueWait"))
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// SetCredentialsExpiryWindow is to set how long before its AWS credentials expire a pooled connection is
+// reported invalid to database/sql, so it is recycled instead of failing the next query. 0 means only expired
+// credentials are invalid. The default is CredentialsExpiryWindow seconds.
+func (c *Config) SetCredentialsExpiryWindow(d time.Duration) {
+	if d >= 0 {
+		c.values.Set("credentialsExpiryWindow", d.String())
+	} else {
+		c.values.Del("credentialsExpiryWindow")
+	}
+}
+
+// GetCredentialsExpiryWindow is getter of CredentialsExpiryWindow.
+func (c *Config) GetCredentialsExpiryWindow() time.Duration {
+	d, err := time.ParseDuration(c.values.Get("credentialsExpiryWindow"))
+	if err != nil || d < 0 {
+		return CredentialsExpiryWindow * time.Second
+	}
+	return d
+}
+
+// SetLogger is to set the Logger for driver output like query cost and query lifecycle messages.
+// Logger is not part of DSN, so use NewSQLConnector with sql.OpenDB() instead of sql.Open() to keep it.
+func (c *Config) SetLogger(l Logger) {
+	c.logger = l
+}
+
+// GetLogger is getter of Logger. If it is not set, a Logger writing to stderr is returned.
+func (c *Config) GetLogger() Logger {
+	if c.logger == nil {
+		return NewStderrLogger()
+	}
+	return c.logger
+}
@@ -24,9 +24,12 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
-	"errors"
+	"encoding/hex"
 	"fmt"
 	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+	"github.com/aws/aws-sdk-go/service/glue/glueiface"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -34,124 +37,126 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/service/athena"
 )
 
 // Connection is a connection to AWS Athena. It is not used concurrently by multiple goroutines.
 // Connection is assumed to be stateful.
 type Connection struct {
-	athenaAPI athenaiface.AthenaAPI
-	connector *SQLConnector
-	numInput  int
+	athenaAPI   athenaiface.AthenaAPI
+	s3API       s3iface.S3API
+	glueAPI     glueiface.GlueAPI
+	credentials *credentials.Credentials
+	connector   *SQLConnector
+	numInput    int
 }
 
 func (c *Connection) interpolateParams(query string, args []driver.Value) (string, error) {
 	c.numInput = len(args)
 	// Number of ? should be same to len(args)
-	if strings.Count(query, "?") != c.numInput {
+	positions, ok := placeholderPositions(query)
+	if !ok || len(positions) != c.numInput {
 		return "", ErrInvalidQuery
 	}
 
 	queryBuffer := make([]byte, MAXQueryStringLength)
 	queryBuffer = queryBuffer[:0]
-	argPos := 0
+	last := 0
 
-	for i := 0; i < len(query); i++ {
-		q := strings.IndexByte(query[i:], '?')
-		if q == -1 {
-			queryBuffer = append(queryBuffer, query[i:]...)
-			break
-		}
-		queryBuffer = append(queryBuffer, query[i:i+q]...)
-		i += q
-
-		arg := args[argPos]
-		argPos++
+	for argPos, q := range positions {
+		queryBuffer = append(queryBuffer, query[last:q]...)
+		last = q + 1
 
-		if arg == nil {
-			queryBuffer = append(queryBuffer, "NULL"...)
-			continue
+		var err error
+		if values, ok := args[argPos].([]driver.Value); ok {
+			queryBuffer, err = appendValues(queryBuffer, values)
+		} else {
+			queryBuffer, err = appendValue(queryBuffer, args[argPos])
 		}
-		// type switches of arg to handle different query parameter types
-		switch v := arg.(type) {
-		case int64:
-			queryBuffer = strconv.AppendInt(queryBuffer, v, 10)
-		case uint64:
-			queryBuffer = strconv.AppendUint(queryBuffer, v, 10)
-		case float64:
-			queryBuffer = strconv.AppendFloat(queryBuffer, v, 'g', -1, 64)
-		case bool:
-			if v {
-				queryBuffer = append(queryBuffer, '1')
-			} else {
-				queryBuffer = append(queryBuffer, '0')
-			}
-		case time.Time:
-			if v.IsZero() {
-				queryBuffer = append(queryBuffer, "'0000-00-00'"...)
-			} else {
-				v := v.In(time.UTC)
-				v = v.Add(time.Nanosecond * 500) // To round under microsecond
-				year := v.Year()
-				year100 := year / 100
-				year1 := year % 100
-				month := v.Month()
-				day := v.Day()
-				hour := v.Hour()
-				minute := v.Minute()
-				second := v.Second()
-				micro := v.Nanosecond() / 1000
-
-				queryBuffer = append(queryBuffer, []byte{
-					'\'',
-					digits10[year100], digits01[year100],
-					digits10[year1], digits01[year1],
-					'-',
-					digits10[month], digits01[month],
-					'-',
-					digits10[day], digits01[day],
-					' ',
-					digits10[hour], digits01[hour],
-					':',
-					digits10[minute], digits01[minute],
-					':',
-					digits10[second], digits01[second],
-				}...)
-
-				if micro != 0 {
-					micro10000 := micro / 10000
-					micro100 := micro / 100 % 100
-					micro1 := micro % 100
-					queryBuffer = append(queryBuffer, []byte{
-						'.',
-						digits10[micro10000], digits01[micro10000],
-						digits10[micro100], digits01[micro100],
-						digits10[micro1], digits01[micro1],
-					}...)
-				}
-				queryBuffer = append(queryBuffer, '\'')
-			}
-		case []byte:
-			queryBuffer = append(queryBuffer, "_binary'"...)
-			queryBuffer = escapeBytesBackslash(queryBuffer, v)
-			queryBuffer = append(queryBuffer, '\'')
-		case string:
-			queryBuffer = append(queryBuffer, '\'')
-			queryBuffer = escapeStringBackslash(queryBuffer, v)
-			queryBuffer = append(queryBuffer, '\'')
-		default:
-			return "", ErrQueryUnknownType
+		if err != nil {
+			return "", err
 		}
 
 		if len(queryBuffer)+4 > 10*MAXQueryStringLength {
 			return "", ErrQueryBufferOF
 		}
 	}
+	queryBuffer = append(queryBuffer, query[last:]...)
 	return string(queryBuffer), nil
 }
 
+// appendValues is to append the elements of a slice argument separated by commas, like `1, 2, 3` for `IN (?)`.
+// An empty slice is appended as NULL, so `IN (?)` matches nothing instead of being invalid SQL.
+func appendValues(queryBuffer []byte, values []driver.Value) ([]byte, error) {
+	if len(values) == 0 {
+		return append(queryBuffer, "NULL"...), nil
+	}
+	var err error
+	for i, v := range values {
+		if i > 0 {
+			queryBuffer = append(queryBuffer, ", "...)
+		}
+		if queryBuffer, err = appendValue(queryBuffer, v); err != nil {
+			return nil, err
+		}
+	}
+	return queryBuffer, nil
+}
+
+// appendValue is to append arg as a SQL literal.
+func appendValue(queryBuffer []byte, arg driver.Value) ([]byte, error) {
+	if arg == nil {
+		return append(queryBuffer, "NULL"...), nil
+	}
+	// type switches of arg to handle different query parameter types
+	switch v := arg.(type) {
+	case int64:
+		queryBuffer = strconv.AppendInt(queryBuffer, v, 10)
+	case uint64:
+		queryBuffer = strconv.AppendUint(queryBuffer, v, 10)
+	case float64:
+		queryBuffer = strconv.AppendFloat(queryBuffer, v, 'g', -1, 64)
+	case bool:
+		queryBuffer = strconv.AppendBool(queryBuffer, v)
+	case time.Time:
+		// Athena uses session timezone(UTC) for TIMESTAMP literal, and its precision is millisecond.
+		queryBuffer = append(queryBuffer, "TIMESTAMP '"...)
+		queryBuffer = v.In(time.UTC).AppendFormat(queryBuffer, TimestampUniXFormat)
+		queryBuffer = append(queryBuffer, '\'')
+	case []byte:
+		// varbinary literal in hexadecimal, which needs no escaping.
+		queryBuffer = append(queryBuffer, "X'"...)
+		pos := len(queryBuffer)
+		queryBuffer = reserveBuffer(queryBuffer, hex.EncodedLen(len(v)))
+		hex.Encode(queryBuffer[pos:], v)
+		queryBuffer = append(queryBuffer, '\'')
+	case string:
+		queryBuffer = append(queryBuffer, '\'')
+		queryBuffer = escapeStringQuotes(queryBuffer, v)
+		queryBuffer = append(queryBuffer, '\'')
+	default:
+		return nil, ErrQueryUnknownType
+	}
+	return queryBuffer, nil
+}
+
 // CheckNamedValue is to implement interface driver.NamedValueChecker.
+// A slice argument other than []byte, like []int or []string, is converted to []driver.Value element by element,
+// so it can be bound to `IN (?)`.
 func (c *Connection) CheckNamedValue(nv *driver.NamedValue) (err error) {
+	if _, ok := nv.Value.(driver.Valuer); !ok {
+		if rv := reflect.ValueOf(nv.Value); rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+			values := make([]driver.Value, rv.Len())
+			for i := range values {
+				if values[i], err = driver.DefaultParameterConverter.ConvertValue(rv.Index(i).Interface()); err != nil {
+					return fmt.Errorf("element %d of argument %d: %w", i, nv.Ordinal, err)
+				}
+			}
+			nv.Value = values
+			return nil
+		}
+	}
 	nv.Value, err = driver.DefaultParameterConverter.ConvertValue(nv.Value)
 	return
 }
@@ -180,14 +185,65 @@ func (c *Connection) ExecContext(ctx context.Context, query string, namedArgs []
 	if r != nil && r.ResultOutput != nil && r.ResultOutput.UpdateCount != nil {
 		rowAffected = *r.ResultOutput.UpdateCount
 	}
+	update := r.UpdateResult()
+	// the result is not needed any more, and it is cleaned up if the driver is configured to. Its pages are
+	// dropped first, so it isn't reported as closed prematurely.
+	r.ResultOutput = nil
+	if err = r.Close(); err != nil {
+		withField(c.connector.config.GetLogger(), "queryID", r.queryID).Errorf("failed to clean up query results: %v",
+			err)
+	}
 	var lastInsertedID int64 = -1
 	result := AthenaResult{
 		lastInsertedID: lastInsertedID,
 		rowAffected:    rowAffected,
+		dataScanned:    r.DataScannedInBytes(),
+		costUSD:        r.CostUSD(),
+		update:         update,
+		submitted:      r.submitted,
+		completed:      r.completed,
 	}
 	return result, nil
 }
 
+// ExecScript is to execute a script of multiple statements separated by semicolons one by one, as Athena
+// only executes one statement per query. Semicolons in string literals, quoted identifiers and comments
+// don't separate statements. It stops at the first failed statement, and returns the results of the
+// statements executed successfully before it together with the error. Use it with sql.Conn.Raw() of Go 1.14+.
+func (c *Connection) ExecScript(ctx context.Context, script string) ([]driver.Result, error) {
+	statements, ok := splitStatements(script)
+	if !ok {
+		return nil, ErrInvalidQuery
+	}
+	results := make([]driver.Result, 0, len(statements))
+	for i, statement := range statements {
+		result, err := c.ExecContext(ctx, statement, nil)
+		if err != nil {
+			return results, fmt.Errorf("statement %d of %d failed: %w", i+1, len(statements), err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// ValidateSQL is to check if query is syntactically and semantically valid without running it, like before
+// saving a query. It runs the query with EXPLAIN, which plans the query but doesn't scan any data, and returns
+// the *QueryError of Athena if the query can't be planned. Use it with sql.Conn.Raw() of Go 1.14+.
+// A query starting with ANALYZE is rejected with ErrInvalidQuery, because EXPLAIN ANALYZE runs the query.
+func (c *Connection) ValidateSQL(ctx context.Context, query string) error {
+	if keyword := leadingKeyword(query); keyword == "" || keyword == "analyze" {
+		return ErrInvalidQuery
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	rows, err := c.QueryContext(ctx, "EXPLAIN "+query, nil)
+	if err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
 // QueryContext is implemented to be called by `DB.Query` (QueryerContext interface).
 //
 // "QueryerContext is an optional interface that may be implemented by a Conn.
@@ -200,13 +256,6 @@ func (c *Connection) ExecContext(ctx context.Context, query string, namedArgs []
 // QueryerContext must honor the context timeout and return when the context is canceled.
 func (c *Connection) QueryContext(ctx context.Context, query string, namedArgs []driver.NamedValue) (driver.Rows, error) {
 	var obs = c.connector.tracer
-	if c.connector.config.IsReadOnly() {
-		if !isReadOnlyStatement(query) {
-			obs.Scope().Counter(DriverName + ".failure.querycontext.writeviolation").Inc(1)
-			obs.Log(WarnLevel, "write db violation", zap.String("query", query))
-			return nil, fmt.Errorf("writing to Athena database is disallowed in read-only mode")
-		}
-	}
 	now := time.Now()
 	args := namedValueToValue(namedArgs)
 	var err error
@@ -217,18 +266,52 @@ func (c *Connection) QueryContext(ctx context.Context, query string, namedArgs [
 		}
 		obs.Scope().Counter(DriverName + ".prepared.querycontext").Inc(1)
 	}
+	if format, ok := ctx.Value(ResultFormatKey).(string); ok && format != "" {
+		query, err = withResultFormat(query, format)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// checked after interpolation, so that the query sent to Athena is checked
+	if c.connector.config.IsReadOnly() && !isReadOnlyStatement(query) {
+		obs.Scope().Counter(DriverName + ".failure.querycontext.writeviolation").Inc(1)
+		obs.Log(WarnLevel, "write db violation", zap.String("query", query))
+		return nil, ErrReadOnly
+	}
 	if !isQueryValid(query) {
 		return nil, ErrInvalidQuery
 	}
 	wg := c.connector.config.GetWorkgroup()
+	if wgName, ok := ctx.Value(WorkgroupKey).(string); ok && wgName != "" {
+		if !isValidWGName(wgName) {
+			return nil, ErrInvalidWorkgroupName
+		}
+		wg.Name = wgName
+	}
+	var remoteWG *athena.WorkGroup
+	engineVersion := c.connector.config.GetEngineVersion()
 	if wg.Name == "" {
 		wg.Name = DefaultWGName
-	} else if wg.Name != DefaultWGName {
-		athenaWG, err := getWG(ctx, c.athenaAPI, wg.Name)
+	}
+	if wg.Name == DefaultWGName {
+		if engineVersion != "" {
+			athenaWG, err := c.getWorkgroup(ctx, wg.Name)
+			if err == nil {
+				err = checkEngineVersion(wg.Name, athenaWG, engineVersion)
+			}
+			if err != nil {
+				obs.Scope().Counter(DriverName + ".failure.querycontext.engineversion").Inc(1)
+				return nil, err
+			}
+			remoteWG = athenaWG
+		}
+	} else {
+		athenaWG, err := c.getWorkgroup(ctx, wg.Name)
 		if err != nil {
 			obs.Scope().Counter(DriverName + ".failure.querycontext.getwg").Inc(1)
 			obs.Log(WarnLevel, "Didn't find workgroup "+wg.Name+" due to: "+err.Error())
 			if c.connector.config.IsWGRemoteCreationAllowed() {
+				wg.Config = withEngineVersion(wg.Config, engineVersion)
 				err = wg.CreateWGRemotely(c.athenaAPI)
 				if err != nil {
 					obs.Scope().Counter(DriverName + ".failure.querycontext.createwgremotely").Inc(1)
@@ -247,23 +330,48 @@ func (c *Connection) QueryContext(ctx context.Context, query string, namedArgs [
 				return nil, fmt.Errorf("workgroup %q is disabled", wg.Name)
 			}
 			obs.Log(DebugLevel, "workgroup "+DefaultWGName+" is enabled.")
+			if err = checkEngineVersion(wg.Name, athenaWG, engineVersion); err != nil {
+				obs.Scope().Counter(DriverName + ".failure.querycontext.engineversion").Inc(1)
+				return nil, err
+			}
+			remoteWG = athenaWG
 		}
 	}
+	resultConfiguration, err := c.resultConfiguration(ctx, wg.Name, remoteWG)
+	if err != nil {
+		obs.Scope().Counter(DriverName + ".failure.querycontext.outputlocation").Inc(1)
+		return nil, err
+	}
 
 	timeWorkgroup := time.Since(now)
-	startOfStartQueryExecution := time.Now()
 	obs.Scope().Timer(DriverName + ".query.workgroup").Record(timeWorkgroup)
 
-	resp, err := c.athenaAPI.StartQueryExecution(&athena.StartQueryExecutionInput{
-		QueryString: aws.String(query),
-		QueryExecutionContext: &athena.QueryExecutionContext{
-			Database: aws.String(c.connector.config.GetDB()),
-		},
-		ResultConfiguration: &athena.ResultConfiguration{
-			OutputLocation: aws.String(c.connector.config.GetOutputBucket()),
-		},
-		WorkGroup: aws.String(wg.Name),
-	})
+	startOfQuerySlot := time.Now()
+	releaseQuerySlot, err := c.connector.acquireQuerySlot(ctx)
+	if err != nil {
+		obs.Log(WarnLevel, "no query slot before context is done", zap.String("query", query))
+		obs.Scope().Counter(DriverName + ".failure.querycontext.queryslot").Inc(1)
+		return nil, err
+	}
+	// released once the query finishes in Athena, or when returning earlier
+	defer releaseQuerySlot()
+	obs.Scope().Timer(DriverName + ".query.queryslot").Record(time.Since(startOfQuerySlot))
+
+	startOfStartQueryExecution := time.Now()
+
+	clientRequestToken := getClientRequestToken(ctx, query)
+	startQueryExecution := func(token *string) (*athena.StartQueryExecutionOutput, error) {
+		return c.athenaAPI.StartQueryExecution(&athena.StartQueryExecutionInput{
+			QueryString: aws.String(query),
+			QueryExecutionContext: &athena.QueryExecutionContext{
+				Database: aws.String(c.connector.config.GetDB()),
+			},
+			ResultConfiguration: resultConfiguration,
+			WorkGroup:           aws.String(wg.Name),
+			ClientRequestToken:  token,
+		})
+	}
+	resp, err := startQueryExecution(clientRequestToken)
 	if err != nil {
 		return nil, err
 	}
@@ -273,12 +381,26 @@ func (c *Connection) QueryContext(ctx context.Context, query string, namedArgs [
 	obs.Scope().Timer(DriverName + ".query.startqueryexecution").Record(timeStartQueryExecution)
 
 	queryID := *resp.QueryExecutionId
+	logger := withField(c.connector.config.GetLogger(), "queryID", queryID)
+	logger.Debugf("query started in workgroup %s", wg.Name)
+	var outputLocation *string
+	var dataScanned int64
+	var manifest string
+	var status *athena.QueryExecutionStatus
+	// queryIDs are the QueryExecutionIds of the query failed with S3 access denied on its output location
+	var queryIDs []string
+	maxQueueWait := c.connector.config.GetMaxQueueWait()
+	maxScannedBytes := c.connector.config.GetMaxScannedBytes()
 WAITING_FOR_RESULT:
 	for {
 		statusResp, err := c.athenaAPI.GetQueryExecutionWithContext(ctx, &athena.GetQueryExecutionInput{
 			QueryExecutionId: aws.String(queryID),
 		})
 		if err != nil {
+			if ctx.Err() != nil {
+				// the status check was aborted by ctx, but the query is still running in Athena
+				return nil, c.stopQueryExecution(wg.Name, queryID, query, now, ctx.Err())
+			}
 			obs.Log(ErrorLevel, "GetQueryExecutionWithContext failed",
 				zap.String("workgroup", wg.Name),
 				zap.String("queryID", queryID),
@@ -294,8 +416,14 @@ WAITING_FOR_RESULT:
 				zap.String("workgroup", wg.Name),
 				zap.String("queryID", queryID))
 			obs.Scope().Timer(DriverName + ".query.canceled").Record(timeCanceled)
+			logger.Debugf("query cancelled by Athena: %s",
+				aws.StringValue(statusResp.QueryExecution.Status.StateChangeReason))
 			if c.connector.config.IsMoneyWise() {
-				printCost(statusResp)
+				printCost(logger, statusResp, c.connector.config.GetPricePerTB())
+			}
+			if isBytesScannedCutoff(aws.StringValue(statusResp.QueryExecution.Status.StateChangeReason)) {
+				obs.Scope().Counter(DriverName + ".failure.querycontext.bytesscannedcutoff").Inc(1)
+				return nil, c.newBytesScannedCutoffError(ctx, wg.Name, queryID, statusResp)
 			}
 			return nil, context.Canceled
 		case athena.QueryExecutionStateFailed:
@@ -306,45 +434,113 @@ WAITING_FOR_RESULT:
 				zap.String("queryID", queryID),
 				zap.String("reason", reason))
 			obs.Scope().Timer(DriverName + ".query.queryexecutionstatefailed").Record(timeQueryExecutionStateFailed)
-			return nil, errors.New(reason)
+			logger.Debugf("query failed: %s", reason)
+			if isBytesScannedCutoff(reason) {
+				obs.Scope().Counter(DriverName + ".failure.querycontext.bytesscannedcutoff").Inc(1)
+				return nil, c.newBytesScannedCutoffError(ctx, wg.Name, queryID, statusResp)
+			}
+			if isInsertStatement(query) && isUnsupportedInsertFormat(reason) {
+				obs.Scope().Counter(DriverName + ".failure.querycontext.insertformat").Inc(1)
+				return nil, &InsertFormatError{QueryID: queryID, Table: insertTableName(query), Reason: reason}
+			}
+			if retries := c.connector.config.GetOutputAccessDeniedRetries(); retries > 0 && isOutputAccessDenied(reason) {
+				queryIDs = append(queryIDs, queryID)
+				if len(queryIDs) > retries {
+					obs.Scope().Counter(DriverName + ".failure.querycontext.outputaccessdenied").Inc(1)
+					return nil, fmt.Errorf("query failed with S3 access denied on output location after %d retries, "+
+						"query IDs %s: %w", retries, strings.Join(queryIDs, ", "), newQueryError(queryID, statusResp.QueryExecution.Status))
+				}
+				// the bucket policy may not be effective in S3 yet
+				backoff := c.connector.config.GetOutputAccessDeniedRetryBackoff() << (len(queryIDs) - 1)
+				obs.Log(WarnLevel, "QueryExecutionStateFailed with S3 output access denied, retrying",
+					zap.String("workgroup", wg.Name),
+					zap.String("queryID", queryID),
+					zap.Int("retry", len(queryIDs)),
+					zap.Duration("backoff", backoff))
+				obs.Scope().Counter(DriverName + ".query.outputaccessdenied.retry").Inc(1)
+				logger.Infof("query failed with S3 access denied on output location, retry %d of %d in %s",
+					len(queryIDs), retries, backoff)
+				select {
+				case <-time.After(backoff):
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+				resp, err = startQueryExecution(retryClientRequestToken(clientRequestToken, len(queryIDs)))
+				if err != nil {
+					return nil, err
+				}
+				queryID = *resp.QueryExecutionId
+				logger = withField(c.connector.config.GetLogger(), "queryID", queryID)
+				logger.Debugf("query restarted in workgroup %s", wg.Name)
+				now = time.Now()
+				continue WAITING_FOR_RESULT
+			}
+			return nil, newQueryError(queryID, statusResp.QueryExecution.Status)
 		case athena.QueryExecutionStateSucceeded:
+			logger.Debugf("query succeeded")
 			if c.connector.config.IsMoneyWise() {
-				printCost(statusResp)
+				printCost(logger, statusResp, c.connector.config.GetPricePerTB())
 			}
 			timeQueryExecutionStateSucceeded := time.Since(now)
 			obs.Scope().Timer(DriverName + ".query.queryexecutionstatesucceeded").Record(timeQueryExecutionStateSucceeded)
+			if statusResp.QueryExecution.ResultConfiguration != nil {
+				outputLocation = statusResp.QueryExecution.ResultConfiguration.OutputLocation
+			}
+			status = statusResp.QueryExecution.Status
+			if statusResp.QueryExecution.Statistics != nil {
+				dataScanned = aws.Int64Value(statusResp.QueryExecution.Statistics.DataScannedInBytes)
+				manifest = aws.StringValue(statusResp.QueryExecution.Statistics.DataManifestLocation)
+			}
 			break WAITING_FOR_RESULT
-		// for athena.QueryExecutionStateQueued and athena.QueryExecutionStateRunning
+		case athena.QueryExecutionStateQueued:
+			if maxQueueWait > 0 && time.Since(now) >= maxQueueWait {
+				obs.Log(ErrorLevel, "Query queue timeout failure",
+					zap.String("workgroup", wg.Name),
+					zap.String("queryID", queryID),
+					zap.String("query", query))
+				obs.Scope().Counter(DriverName + ".failure.querycontext.queuetimeout").Inc(1)
+				return nil, c.stopQueryExecution(wg.Name, queryID, query, now, ErrQueueTimeout)
+			}
+		// for athena.QueryExecutionStateRunning
 		default:
 		}
 
-		select {
-		case <-ctx.Done():
-			_, err := c.athenaAPI.
-				StopQueryExecutionWithContext(context.Background(), &athena.StopQueryExecutionInput{
-					QueryExecutionId: aws.String(queryID),
-				})
-			if err != nil {
-				obs.Log(ErrorLevel, "StopQueryExecution failed",
+		if maxScannedBytes > 0 && statusResp.QueryExecution.Statistics != nil {
+			if scanned := aws.Int64Value(statusResp.QueryExecution.Statistics.DataScannedInBytes); scanned > maxScannedBytes {
+				obs.Log(ErrorLevel, "Query scan limit failure",
 					zap.String("workgroup", wg.Name),
 					zap.String("queryID", queryID),
-					zap.String("query", query))
-				obs.Scope().Counter(DriverName + ".failure.querycontext.stopqueryexecution.failed").Inc(1)
-				return nil, err
+					zap.Int64("dataScannedInBytes", scanned))
+				obs.Scope().Counter(DriverName + ".failure.querycontext.scanlimitexceeded").Inc(1)
+				logger.Errorf("query scanned %d bytes, exceeding the limit of %d bytes", scanned, maxScannedBytes)
+				return nil, c.stopQueryExecution(wg.Name, queryID, query, now, ErrScanLimitExceeded)
 			}
-			if c.connector.config.IsMoneyWise() {
-				statusRespFinal, _ := c.athenaAPI.GetQueryExecutionWithContext(context.Background(), &athena.GetQueryExecutionInput{
-					QueryExecutionId: aws.String(queryID),
-				})
-				printCost(statusRespFinal)
+		}
+
+		// StatementType may not be reported yet, like when the query is still in QUEUED state
+		queryType := aws.StringValue(statusResp.QueryExecution.StatementType)
+		if queryType == "" {
+			queryType = statementType(query)
+		}
+		deadline, ctxLimited := queryDeadline(ctx, startOfStartQueryExecution, queryType,
+			c.connector.config)
+		wait := PoolInterval * time.Second
+		if untilDeadline := time.Until(deadline); untilDeadline < wait {
+			wait = untilDeadline
+		}
+		if *statusResp.QueryExecution.Status.State == athena.QueryExecutionStateQueued && maxQueueWait > 0 {
+			if untilQueueTimeout := maxQueueWait - time.Since(now); untilQueueTimeout < wait {
+				wait = untilQueueTimeout
 			}
-			obs.Scope().Counter(DriverName + ".failure.querycontext.stopqueryexecution.succeeded").Inc(1)
-			timeStopQueryExecution := time.Since(now)
-			obs.Scope().Timer(DriverName + ".query.StopQueryExecution").Record(timeStopQueryExecution)
-			obs.Log(ErrorLevel, "query canceled", zap.String("queryID", queryID))
-			return nil, ctx.Err()
-		case <-time.After(PoolInterval * time.Second):
-			if isQueryTimeOut(startOfStartQueryExecution, *statusResp.QueryExecution.StatementType) {
+		}
+		select {
+		case <-ctx.Done():
+			return nil, c.stopQueryExecution(wg.Name, queryID, query, now, ctx.Err())
+		case <-time.After(wait):
+			if ctxLimited && !time.Now().Before(deadline) {
+				return nil, c.stopQueryExecution(wg.Name, queryID, query, now, context.DeadlineExceeded)
+			}
+			if isQueryTimeOut(startOfStartQueryExecution, queryType, c.connector.config) {
 				obs.Log(ErrorLevel, "Query timeout failure",
 					zap.String("workgroup", wg.Name),
 					zap.String("queryID", queryID),
@@ -356,7 +552,75 @@ WAITING_FOR_RESULT:
 		}
 	}
 
-	return NewRows(ctx, c.athenaAPI, queryID, c.connector.config, obs)
+	releaseQuerySlot()
+
+	rows, err := NewRows(ctx, c.athenaAPI, queryID, c.connector.config, obs)
+	if err != nil {
+		return nil, err
+	}
+	rows.outputLocation = outputLocation
+	rows.dataScanned = dataScanned
+	rows.manifest = manifest
+	rows.submitted = status.SubmissionDateTime
+	rows.completed = status.CompletionDateTime
+	rows.updateType = updateType(query)
+	if c.connector.config.IsCleanupResults() && c.s3API != nil {
+		// only the result of the query started above is deleted, never the result of a resumed query
+		rows.s3API = c.s3API
+	}
+	rows.glueAPI = c.glueAPI
+	return rows, nil
+}
+
+// stopQueryExecution is to stop a query which is still running in Athena after its context is done.
+// It returns ctxErr if the query is stopped successfully, otherwise the error of StopQueryExecution.
+func (c *Connection) stopQueryExecution(wgName string, queryID string, query string, now time.Time,
+	ctxErr error) error {
+	var obs = c.connector.tracer
+	logger := withField(c.connector.config.GetLogger(), "queryID", queryID)
+	_, err := c.athenaAPI.
+		StopQueryExecutionWithContext(context.Background(), &athena.StopQueryExecutionInput{
+			QueryExecutionId: aws.String(queryID),
+		})
+	if err != nil {
+		obs.Log(ErrorLevel, "StopQueryExecution failed",
+			zap.String("workgroup", wgName),
+			zap.String("queryID", queryID),
+			zap.String("query", query))
+		obs.Scope().Counter(DriverName + ".failure.querycontext.stopqueryexecution.failed").Inc(1)
+		logger.Errorf("failed to stop query: %v", err)
+		return err
+	}
+	if c.connector.config.IsMoneyWise() {
+		statusRespFinal, _ := c.athenaAPI.GetQueryExecutionWithContext(context.Background(), &athena.GetQueryExecutionInput{
+			QueryExecutionId: aws.String(queryID),
+		})
+		printCost(logger, statusRespFinal, c.connector.config.GetPricePerTB())
+	}
+	obs.Scope().Counter(DriverName + ".failure.querycontext.stopqueryexecution.succeeded").Inc(1)
+	timeStopQueryExecution := time.Since(now)
+	obs.Scope().Timer(DriverName + ".query.StopQueryExecution").Record(timeStopQueryExecution)
+	obs.Log(ErrorLevel, "query canceled", zap.String("queryID", queryID))
+	logger.Debugf("query stopped: %v", ctxErr)
+	return ctxErr
+}
+
+// newBytesScannedCutoffError is to build a BytesScannedCutoffError from the final query status.
+// The limit is looked up from the workgroup configuration; it is left as 0 if that fails.
+func (c *Connection) newBytesScannedCutoffError(ctx context.Context, wgName string, queryID string,
+	statusResp *athena.GetQueryExecutionOutput) error {
+	e := &BytesScannedCutoffError{
+		QueryID: queryID,
+		Reason:  aws.StringValue(statusResp.QueryExecution.Status.StateChangeReason),
+	}
+	if statusResp.QueryExecution.Statistics != nil {
+		e.DataScannedInBytes = aws.Int64Value(statusResp.QueryExecution.Statistics.DataScannedInBytes)
+	}
+	athenaWG, err := getWG(ctx, c.athenaAPI, wgName)
+	if err == nil && athenaWG.Configuration != nil {
+		e.BytesScannedCutoff = aws.Int64Value(athenaWG.Configuration.BytesScannedCutoffPerQuery)
+	}
+	return e
 }
 
 // Ping implements driver.Pinger interface.
@@ -383,7 +647,7 @@ func (c *Connection) Prepare(query string) (driver.Stmt, error) {
 		connection: c,
 		query:      query,
 		closed:     false,
-		numInput:   strings.Count(query, "?"),
+		numInput:   countPlaceholders(query),
 	}
 	return stmt, nil
 }
@@ -406,9 +670,43 @@ func (c *Connection) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx,
 func (c *Connection) Close() error {
 	c.connector = nil
 	c.athenaAPI = nil
+	c.s3API = nil
+	c.glueAPI = nil
+	c.credentials = nil
 	c.numInput = -1
 	return nil
 }
 
+// isValid is to check if the connection can be reused. The connection is invalid if it is closed, or its AWS
+// credentials have expired or expire within CredentialsExpiryWindow, so it is recycled instead of failing the
+// next query after being idle. Credentials without expiry, like static ones, never make it invalid.
+func (c *Connection) isValid() bool {
+	if c.connector == nil || c.athenaAPI == nil {
+		return false
+	}
+	if c.credentials == nil {
+		return true
+	}
+	expiresAt, err := c.credentials.ExpiresAt()
+	if err != nil || expiresAt.IsZero() {
+		// the provider doesn't expire, or the credentials are not retrieved yet
+		return true
+	}
+	return time.Until(expiresAt) > c.connector.config.GetCredentialsExpiryWindow()
+}
+
+// ResetSession is to implement interface driver.SessionResetter. database/sql calls it before reusing a
+// pooled connection, and discards it if driver.ErrBadConn is returned.
+func (c *Connection) ResetSession(ctx context.Context) error {
+	if !c.isValid() {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+// database/sql calls QueryContext and ExecContext directly without Prepare, with or without args.
 var _ driver.QueryerContext = (*Connection)(nil)
 var _ driver.ExecerContext = (*Connection)(nil)
+var _ driver.NamedValueChecker = (*Connection)(nil)
+var _ driver.Pinger = (*Connection)(nil)
+var _ driver.SessionResetter = (*Connection)(nil)
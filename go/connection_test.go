@@ -24,6 +24,7 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"errors"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -107,6 +108,20 @@ func TestReadOnlyDROP(t *testing.T) {
 	assert.Equal(t, err.Error(), "writing to Athena database is disallowed in read-only mode")
 }
 
+func TestReadOnlyErr(t *testing.T) {
+	c := createConnectionFixture()
+	c.connector.config.SetReadOnly(true)
+	for _, query := range []string{"/* SELECT */ INSERT INTO t VALUES (1)", "UNLOAD (SELECT 1) TO 's3://b/'",
+		"EXPLAIN ANALYZE DROP TABLE t"} {
+		_, err := c.QueryContext(context.Background(), query, []driver.NamedValue{})
+		assert.Equal(t, ErrReadOnly, err, query)
+		_, err = c.ExecContext(context.Background(), query, []driver.NamedValue{})
+		assert.True(t, errors.Is(err, ErrReadOnly), query)
+	}
+	_, err := c.QueryContext(context.Background(), "SELECT 1", []driver.NamedValue{})
+	assert.Nil(t, err)
+}
+
 func TestConnection_Prepare(t *testing.T) {
 	testConf := NewNoOpsConfig()
 	connector := &SQLConnector{
@@ -156,6 +171,54 @@ func TestConnection_Close(t *testing.T) {
 
 }
 
+// expiringProvider is a credentials.Provider whose credentials expire at expiresAt.
+type expiringProvider struct {
+	expiresAt time.Time
+}
+
+func (p *expiringProvider) Retrieve() (credentials.Value, error) {
+	return credentials.Value{AccessKeyID: "id", SecretAccessKey: "key", ProviderName: "expiringProvider"}, nil
+}
+
+func (p *expiringProvider) IsExpired() bool {
+	return time.Now().After(p.expiresAt)
+}
+
+func (p *expiringProvider) ExpiresAt() time.Time {
+	return p.expiresAt
+}
+
+func TestConnection_IsValid(t *testing.T) {
+	c := createConnectionFixture()
+	assert.True(t, c.isValid())
+	assert.Nil(t, c.ResetSession(context.Background()))
+
+	// static credentials never expire
+	c.credentials = credentials.NewStaticCredentials("id", "key", "")
+	_, _ = c.credentials.Get()
+	assert.True(t, c.isValid())
+
+	provider := &expiringProvider{expiresAt: time.Now().Add(time.Hour)}
+	c.credentials = credentials.NewCredentials(provider)
+	// not retrieved yet
+	assert.True(t, c.isValid())
+	_, _ = c.credentials.Get()
+	assert.True(t, c.isValid())
+
+	// expire within the window
+	provider.expiresAt = time.Now().Add(time.Minute)
+	assert.False(t, c.isValid())
+	assert.Equal(t, driver.ErrBadConn, c.ResetSession(context.Background()))
+	c.connector.config.SetCredentialsExpiryWindow(0)
+	assert.True(t, c.isValid())
+	provider.expiresAt = time.Now().Add(-time.Minute)
+	assert.False(t, c.isValid())
+
+	assert.Nil(t, c.Close())
+	assert.False(t, c.isValid())
+	assert.Equal(t, driver.ErrBadConn, c.ResetSession(context.Background()))
+}
+
 func TestConnection_QueryContext(t *testing.T) {
 	testConf := NewNoOpsConfig()
 	connector := &SQLConnector{
@@ -229,13 +292,66 @@ func TestConnection_InterpolateParams_Query2(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+// assertInterpolatedLiterals checks that bound values stay inside their literals: the interpolated query
+// must tokenize cleanly and have no more placeholders than expected outside of literals and comments.
+func assertInterpolatedLiterals(t *testing.T, q string, placeholders int) {
+	_, ok := sqlTokens(q)
+	assert.True(t, ok, q)
+	assert.Equal(t, placeholders, countPlaceholders(q), q)
+}
+
+func TestConnection_InterpolateParams_Quotes(t *testing.T) {
+	c := createTestConnection(t)
+	tests := map[string]string{
+		"it's":              `'it''s'`,
+		"'":                 `''''`,
+		`trailing\`:         `'trailing\'`,
+		`\' OR 1=1 --`:      `'\'' OR 1=1 --'`,
+		"a -- b":            `'a -- b'`,
+		"a /* ? */ ? b":     `'a /* ? */ ? b'`,
+		"line\nbreak -- x'": "'line\nbreak -- x'''",
+	}
+	for arg, expected := range tests {
+		q, err := c.interpolateParams("SELECT ? FROM t WHERE x = ?", []driver.Value{arg, int64(1)})
+		assert.Nil(t, err, arg)
+		assert.Equal(t, "SELECT "+expected+" FROM t WHERE x = 1", q, arg)
+		assertInterpolatedLiterals(t, q, 0)
+		// the bound value is a single string literal token
+		tokens, _ := sqlTokens(q)
+		assert.Equal(t, expected, q[tokens[1].start:tokens[1].end], arg)
+	}
+}
+
+func TestConnection_InterpolateParams_Slice(t *testing.T) {
+	c := createTestConnection(t)
+	q, err := c.interpolateParams("SELECT * FROM t WHERE id IN (?) AND name IN (?)", []driver.Value{
+		[]driver.Value{int64(1), int64(2), int64(3)}, []driver.Value{"a", "b'; DROP TABLE t; --", nil}})
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT * FROM t WHERE id IN (1, 2, 3) AND name IN ('a', 'b''; DROP TABLE t; --', NULL)`, q)
+	assertInterpolatedLiterals(t, q, 0)
+
+	q, err = c.interpolateParams("SELECT * FROM t WHERE name IN (?) AND id = ?", []driver.Value{
+		[]driver.Value{`a\`, "' OR 1=1 --"}, int64(1)})
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT * FROM t WHERE name IN ('a\', ''' OR 1=1 --') AND id = 1`, q)
+	assertInterpolatedLiterals(t, q, 0)
+
+	// an empty slice matches nothing
+	q, err = c.interpolateParams("SELECT * FROM t WHERE id IN (?)", []driver.Value{[]driver.Value{}})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM t WHERE id IN (NULL)", q)
+
+	_, err = c.interpolateParams("?", []driver.Value{[]driver.Value{[]driver.Value{int64(1)}}})
+	assert.Equal(t, ErrQueryUnknownType, err)
+}
+
 func TestConnection_InterpolateParams_Bool(t *testing.T) {
 	c := createTestConnection(t)
 	q, err := c.interpolateParams("?", []driver.Value{true})
-	assert.Equal(t, q, "1")
+	assert.Equal(t, q, "true")
 	assert.Nil(t, err)
 	q, err = c.interpolateParams("?", []driver.Value{false})
-	assert.Equal(t, q, "0")
+	assert.Equal(t, q, "false")
 	assert.Nil(t, err)
 	q, err = c.interpolateParams("?", []driver.Value{int64(1)})
 	assert.Equal(t, q, "1")
@@ -247,13 +363,20 @@ func TestConnection_InterpolateParams_Bool(t *testing.T) {
 	assert.Equal(t, q, "1.1")
 	assert.Nil(t, err)
 	q, err = c.interpolateParams("?", []driver.Value{time.Time{}})
-	assert.Equal(t, q, "'0000-00-00'")
+	assert.Equal(t, q, "TIMESTAMP '0001-01-01 00:00:00.000'")
 	assert.Nil(t, err)
-	q, err = c.interpolateParams("?", []driver.Value{time.Now()})
-	assert.NotEqual(t, q, "'0000-00-00'")
+	loc := time.FixedZone("UTC+8", 8*60*60)
+	q, err = c.interpolateParams("?", []driver.Value{time.Date(2020, 3, 4, 13, 14, 15, 123456789, loc)})
+	assert.Equal(t, q, "TIMESTAMP '2020-03-04 05:14:15.123'")
 	assert.Nil(t, err)
 	q, err = c.interpolateParams("?", []driver.Value{[]byte{'0'}})
-	assert.Equal(t, q, "_binary'0'")
+	assert.Equal(t, q, "X'30'")
+	assert.Nil(t, err)
+	q, err = c.interpolateParams("?", []driver.Value{[]byte("'\\\x00")})
+	assert.Equal(t, q, "X'275c00'")
+	assert.Nil(t, err)
+	q, err = c.interpolateParams("?", []driver.Value{[]byte{}})
+	assert.Equal(t, q, "X''")
 	assert.Nil(t, err)
 	q, err = c.interpolateParams("?", []driver.Value{nil})
 	assert.Equal(t, q, "NULL")
@@ -262,20 +385,26 @@ func TestConnection_InterpolateParams_Bool(t *testing.T) {
 	assert.Equal(t, q, "123NULL4")
 	assert.Nil(t, err)
 	q, err = c.interpolateParams("?", []driver.Value{time.Time{}.Add(1 * time.Nanosecond)})
-	assert.Equal(t, q, "'0001-01-01 00:00:00'")
+	assert.Equal(t, q, "TIMESTAMP '0001-01-01 00:00:00.000'")
 	assert.Nil(t, err)
 }
 
-// We don't support placeholder in string literal for now.
+// Placeholders in string literals, quoted identifiers and comments are not interpolated.
 // https://github.com/go-sql-driver/mysql/pull/490
 func TestInterpolateParamsPlaceholderInString(t *testing.T) {
 	c := createTestConnection(t)
 
 	q, err := c.interpolateParams("SELECT 'abc?xyz',?", []driver.Value{int64(42)})
-	// When InterpolateParams support string literal, this should return `"SELECT 'abc?xyz', 42`
-	if err != ErrInvalidQuery {
-		t.Errorf("Expected err=ErrInvalidQuery, got err=%#v, q=%#v", err, q)
-	}
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT 'abc?xyz',42", q)
+
+	q, err = c.interpolateParams("SELECT \"a?\", ? -- what?\n/* ? */", []driver.Value{"b"})
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT \"a?\", 'b' -- what?\n/* ? */", q)
+
+	q, err = c.interpolateParams("SELECT 'abc?", []driver.Value{int64(42)})
+	assert.Equal(t, ErrInvalidQuery, err)
+	assert.Equal(t, "", q)
 }
 
 func TestInterpolateParamsUint64(t *testing.T) {
@@ -294,6 +423,31 @@ func TestCheckNamedValue(t *testing.T) {
 	assert.Equal(t, value.Value, int64(0))
 }
 
+func TestCheckNamedValue_Slice(t *testing.T) {
+	c := createTestConnection(t)
+	value := driver.NamedValue{Ordinal: 1, Value: []int{1, 2}}
+	assert.Nil(t, c.CheckNamedValue(&value))
+	assert.Equal(t, []driver.Value{int64(1), int64(2)}, value.Value)
+
+	value = driver.NamedValue{Ordinal: 1, Value: []string{}}
+	assert.Nil(t, c.CheckNamedValue(&value))
+	assert.Equal(t, []driver.Value{}, value.Value)
+
+	value = driver.NamedValue{Ordinal: 1, Value: [][]byte{{1}}}
+	assert.Nil(t, c.CheckNamedValue(&value))
+	assert.Equal(t, []driver.Value{[]byte{1}}, value.Value)
+
+	// []byte is varbinary
+	value = driver.NamedValue{Ordinal: 1, Value: []byte{1}}
+	assert.Nil(t, c.CheckNamedValue(&value))
+	assert.Equal(t, []byte{1}, value.Value)
+
+	value = driver.NamedValue{Ordinal: 2, Value: [][]int{{1}}}
+	err := c.CheckNamedValue(&value)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "element 0 of argument 2")
+}
+
 func createTestConnection(t *testing.T) *Connection {
 	t.Parallel()
 	testConf := NewNoOpsConfig()
@@ -546,6 +700,12 @@ func TestConnection_QueryContext7(t *testing.T) {
 	driverRows, err = c.QueryContext(context.Background(), query, []driver.NamedValue{})
 	assert.NotNil(t, err)
 	assert.Nil(t, driverRows)
+	var queryErr *QueryError
+	assert.True(t, errors.As(err, &queryErr))
+	assert.Equal(t, &QueryError{QueryID: "SELECTQueryContext_AWS_FAIL_QID", Category: ErrorCategoryOther,
+		Reason: "something_broken"}, queryErr)
+	assert.Equal(t, "query SELECTQueryContext_AWS_FAIL_QID failed: something_broken", err.Error())
+	assert.False(t, queryErr.IsUserError() || queryErr.IsSystemError())
 
 	query = "SELECTQueryContext_CANCEL_OK"
 	ctx, cancel = context.WithTimeout(context.Background(), PoolInterval*time.Second*2)
@@ -555,6 +715,671 @@ func TestConnection_QueryContext7(t *testing.T) {
 	assert.Nil(t, driverRows)
 }
 
+func TestConnection_QueryContextWorkgroupOutputLocation(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+	nm := c.athenaAPI.(*mockAthenaClient)
+	nm.GetWGStatus = true
+
+	// the workgroup enforces its configuration, and is got only once while it is cached
+	_, err := c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Nil(t, nm.lastStartQueryExecutionInput.ResultConfiguration)
+	_, err = c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, nm.getWGCount)
+
+	c = createConnectionFixture()
+	nm = c.athenaAPI.(*mockAthenaClient)
+	nm.GetWGStatus = true
+	nm.wgConfig = NewWGConfig(DefaultBytesScannedCutoffPerQuery, false, true, false, nil)
+	_, err = c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Equal(t, "s3://query-results-henry-wu-us-east-2/",
+		*nm.lastStartQueryExecutionInput.ResultConfiguration.OutputLocation)
+
+	// no output location in DSN
+	c.connector = NoopsSQLConnector()
+	_, err = c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.True(t, errors.Is(err, ErrConfigOutputLocationNotFound))
+	assert.Contains(t, err.Error(), `workgroup "primary"`)
+
+	c.connector = NoopsSQLConnector()
+	nm.wgConfig = NewWGConfig(DefaultBytesScannedCutoffPerQuery, false, true, false,
+		&athena.ResultConfiguration{OutputLocation: aws.String("s3://wg-results/")})
+	_, err = c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Nil(t, nm.lastStartQueryExecutionInput.ResultConfiguration)
+
+	// leave it to Athena if the workgroup can't be got
+	c.connector = NoopsSQLConnector()
+	nm.GetWGStatus = false
+	_, err = c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Nil(t, nm.lastStartQueryExecutionInput.ResultConfiguration)
+}
+
+func TestConnection_GetWorkgroupCacheExpiry(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+	nm := c.athenaAPI.(*mockAthenaClient)
+	nm.GetWGStatus = true
+
+	_, err := c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, nm.getWGCount)
+
+	// the workgroup is disabled after it is cached, which is noticed once the cache expires
+	nm.WGDisabled = true
+	_, err = c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	w, _ := c.connector.workgroups.Load("henry_wu")
+	cached := w.(cachedWorkgroup)
+	cached.expiry = time.Now().Add(-time.Second)
+	c.connector.workgroups.Store("henry_wu", cached)
+	_, err = c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Equal(t, `workgroup "henry_wu" is disabled`, err.Error())
+	assert.Equal(t, 2, nm.getWGCount)
+	_, ok := c.connector.workgroups.Load("henry_wu")
+	assert.False(t, ok)
+}
+
+func TestConnection_QueryContextEngineVersion(t *testing.T) {
+	t.Parallel()
+	engineVersion := func(v string) *athena.WorkGroupConfiguration {
+		config := GetDefaultWGConfig()
+		config.EngineVersion = &athena.EngineVersion{
+			SelectedEngineVersion:  aws.String("AUTO"),
+			EffectiveEngineVersion: aws.String(v),
+		}
+		return config
+	}
+	c := createConnectionFixture()
+	nm := c.athenaAPI.(*mockAthenaClient)
+	nm.GetWGStatus = true
+	nm.wgConfig = engineVersion("Athena engine version 2")
+
+	// no engine version is configured
+	_, err := c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+
+	c = createConnectionFixture()
+	nm = c.athenaAPI.(*mockAthenaClient)
+	nm.GetWGStatus = true
+	nm.wgConfig = engineVersion("Athena engine version 2")
+	c.connector.config.SetEngineVersion("Athena engine version 3")
+	_, err = c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.True(t, errors.Is(err, ErrEngineVersionMismatch))
+	assert.Contains(t, err.Error(), `workgroup "henry_wu" runs "Athena engine version 2"`)
+	assert.Equal(t, 0, nm.startQueryExecutionCount)
+
+	nm.wgConfig = engineVersion("Athena engine version 3")
+	c.connector.workgroups.Delete("henry_wu")
+	_, err = c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+
+	// the primary workgroup is checked too
+	c.connector = NoopsSQLConnector()
+	assert.Nil(t, c.connector.config.SetOutputBucket("s3://query-results-henry-wu-us-east-2/"))
+	c.connector.config.SetEngineVersion("Athena engine version 2")
+	_, err = c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.True(t, errors.Is(err, ErrEngineVersionMismatch))
+	assert.Contains(t, err.Error(), `workgroup "primary"`)
+
+	// a workgroup created remotely gets the engine version
+	c = createConnectionFixture()
+	nm = c.athenaAPI.(*mockAthenaClient)
+	c.connector.config.SetEngineVersion("Athena engine version 3")
+	_, err = c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Equal(t, "henry_wu", *nm.lastCreateWorkGroupInput.Name)
+	assert.Equal(t, "Athena engine version 3",
+		*nm.lastCreateWorkGroupInput.Configuration.EngineVersion.SelectedEngineVersion)
+}
+
+func TestConnection_QueryContextOutputPrefixTemplate(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+	nm := c.athenaAPI.(*mockAthenaClient)
+	assert.Nil(t, c.connector.config.SetOutputPrefixTemplate("athena-results/{yyyy}/{mm}/{dd}/"))
+
+	_, err := c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Regexp(t, `^s3://query-results-henry-wu-us-east-2/athena-results/\d{4}/\d{2}/\d{2}/$`,
+		*nm.lastStartQueryExecutionInput.ResultConfiguration.OutputLocation)
+}
+
+func TestConnection_QueryContextClientRequestToken(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+	nm := c.athenaAPI.(*mockAthenaClient)
+
+	_, err := c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Nil(t, nm.lastStartQueryExecutionInput.ClientRequestToken)
+
+	ctx := context.WithValue(context.Background(), IdempotencyKey, "submission-1")
+	_, err = c.QueryContext(ctx, "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Equal(t, getClientRequestToken(ctx, "SELECTQueryContext_OK"),
+		nm.lastStartQueryExecutionInput.ClientRequestToken)
+}
+
+func TestConnection_QueryContextWorkgroupOverride(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+	nm := c.athenaAPI.(*mockAthenaClient)
+	nm.GetWGStatus = true
+
+	_, err := c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Equal(t, "henry_wu", *nm.lastStartQueryExecutionInput.WorkGroup)
+
+	ctx := context.WithValue(context.Background(), WorkgroupKey, "batch.wg-1")
+	_, err = c.QueryContext(ctx, "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Equal(t, "batch.wg-1", *nm.lastStartQueryExecutionInput.WorkGroup)
+
+	ctx = context.WithValue(context.Background(), WorkgroupKey, "batch wg")
+	driverRows, err := c.QueryContext(ctx, "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, driverRows)
+	assert.Equal(t, ErrInvalidWorkgroupName, err)
+}
+
+func TestConnection_QueryContextDeadline(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+
+	// ctx deadline fires before the statement timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	driverRows, err := c.QueryContext(ctx, "SELECTQueryContext_CANCEL_OK", []driver.NamedValue{})
+	assert.Nil(t, driverRows)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// statement timeout fires before the ctx deadline
+	ctx, cancel = context.WithTimeout(context.Background(), 1*time.Hour)
+	defer cancel()
+	driverRows, err = c.QueryContext(ctx, "SELECTQueryContext_TIMEOUT", []driver.NamedValue{})
+	assert.Nil(t, driverRows)
+	assert.Equal(t, ErrQueryTimeout, err)
+}
+
+func TestConnection_QueryContextQueueTimeout(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+	c.connector.config.SetMaxQueueWait(100 * time.Millisecond)
+
+	start := time.Now()
+	driverRows, err := c.QueryContext(context.Background(), "SELECTQueryContext_CANCEL_OK", []driver.NamedValue{})
+	assert.Nil(t, driverRows)
+	assert.Equal(t, ErrQueueTimeout, err)
+	assert.True(t, time.Since(start) < PoolInterval*time.Second)
+
+	// failed to stop the query
+	driverRows, err = c.QueryContext(context.Background(), "SELECTQueryContext_CANCEL_FAIL", []driver.NamedValue{})
+	assert.Nil(t, driverRows)
+	assert.Equal(t, ErrTestMockGeneric, err)
+}
+
+func TestConnection_QueryContextScanLimit(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+	logger := &testLogger{}
+	c.connector.config.SetLogger(logger)
+
+	// the query stays in QUEUED with 123 bytes scanned
+	c.connector.config.SetMaxScannedBytes(100)
+	driverRows, err := c.QueryContext(context.Background(), "SELECTQueryContext_CANCEL_OK", []driver.NamedValue{})
+	assert.Nil(t, driverRows)
+	assert.Equal(t, ErrScanLimitExceeded, err)
+	assert.Equal(t, []string{"query scanned 123 bytes, exceeding the limit of 100 bytes"}, logger.errors)
+
+	// completed queries are not stopped
+	driverRows, err = c.QueryContext(context.Background(), "SELECT 1", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.NotNil(t, driverRows)
+
+	c.connector.config.SetMaxScannedBytes(1000)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	driverRows, err = c.QueryContext(ctx, "SELECTQueryContext_CANCEL_OK", []driver.NamedValue{})
+	assert.Nil(t, driverRows)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestConnection_QueryContextCleanupResults(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+	ms3 := &mockS3Client{}
+	c.s3API = ms3
+
+	// disabled by default
+	driverRows, err := c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Nil(t, driverRows.Close())
+	assert.Nil(t, ms3.deletedKeys)
+
+	c.connector.config.SetCleanupResults(true)
+	driverRows, err = c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Nil(t, ms3.deletedKeys)
+	assert.Nil(t, driverRows.Close())
+	assert.Equal(t, "query-results-henry-wu-us-east-2", ms3.deletedBucket)
+	assert.Equal(t, []string{"SELECTQueryContext_OK_QID.csv", "SELECTQueryContext_OK_QID.csv.metadata"},
+		ms3.deletedKeys)
+	// only once
+	assert.Nil(t, driverRows.Close())
+	assert.Len(t, ms3.deletedKeys, 2)
+
+	// no output location reported
+	ms3.deletedKeys = nil
+	driverRows, err = c.QueryContext(context.Background(), "SELECT 1", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Nil(t, driverRows.Close())
+	assert.Nil(t, ms3.deletedKeys)
+
+	ms3.failedKey = "SELECTQueryContext_OK_QID.csv.metadata"
+	driverRows, err = c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.NotNil(t, driverRows.Close())
+
+	ms3.deleteErr = ErrTestMockGeneric
+	driverRows, err = c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Equal(t, ErrTestMockGeneric, driverRows.Close())
+}
+
+func TestConnection_QueryContextAclOption(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+	nm := c.athenaAPI.(*mockAthenaClient)
+
+	// disabled by default
+	_, err := c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Nil(t, nm.lastStartQueryExecutionInput.ResultConfiguration.AclConfiguration)
+
+	assert.Nil(t, c.connector.config.SetAclOption("bucket_owner_full_control"))
+	_, err = c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Equal(t, "s3://query-results-henry-wu-us-east-2/",
+		*nm.lastStartQueryExecutionInput.ResultConfiguration.OutputLocation)
+	assert.Equal(t, athena.S3AclOptionBucketOwnerFullControl,
+		*nm.lastStartQueryExecutionInput.ResultConfiguration.AclConfiguration.S3AclOption)
+
+	// the output location is left to the workgroup
+	c.connector = NoopsSQLConnector()
+	assert.Nil(t, c.connector.config.SetAclOption("BUCKET_OWNER_FULL_CONTROL"))
+	nm.GetWGStatus = true
+	nm.wgConfig = NewWGConfig(DefaultBytesScannedCutoffPerQuery, false, true, false,
+		&athena.ResultConfiguration{OutputLocation: aws.String("s3://wg-results/")})
+	_, err = c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Nil(t, nm.lastStartQueryExecutionInput.ResultConfiguration.OutputLocation)
+	assert.Equal(t, athena.S3AclOptionBucketOwnerFullControl,
+		*nm.lastStartQueryExecutionInput.ResultConfiguration.AclConfiguration.S3AclOption)
+
+	// the workgroup enforces its configuration
+	c.connector = NoopsSQLConnector()
+	assert.Nil(t, c.connector.config.SetAclOption("BUCKET_OWNER_FULL_CONTROL"))
+	nm.wgConfig = NewWGConfig(DefaultBytesScannedCutoffPerQuery, true, true, false,
+		&athena.ResultConfiguration{OutputLocation: aws.String("s3://wg-results/")})
+	_, err = c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Nil(t, nm.lastStartQueryExecutionInput.ResultConfiguration)
+}
+
+func TestConnection_ValidateSQL(t *testing.T) {
+	c := createConnectionFixture()
+	ma := c.athenaAPI.(*mockAthenaClient)
+	assert.Nil(t, c.ValidateSQL(context.Background(), "SELECT * FROM t"))
+	assert.Equal(t, "EXPLAIN SELECT * FROM t", *ma.lastStartQueryExecutionInput.QueryString)
+
+	err := c.ValidateSQL(context.Background(), "SELECT * FORM t")
+	var queryErr *QueryError
+	assert.True(t, errors.As(err, &queryErr))
+	assert.True(t, queryErr.IsUserError())
+	assert.Equal(t, int64(1006), queryErr.ErrorType)
+	assert.Contains(t, err.Error(), "mismatched input 'FORM'")
+
+	n := ma.startQueryExecutionCount
+	assert.Equal(t, ErrInvalidQuery, c.ValidateSQL(context.Background(), " -- nothing"))
+	assert.Equal(t, ErrInvalidQuery, c.ValidateSQL(context.Background(), "ANALYZE SELECT * FROM t"))
+	assert.Equal(t, ErrInvalidQuery, c.ValidateSQL(context.Background(), "/* x */ (analyze SELECT 1)"))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, c.ValidateSQL(ctx, "SELECT * FROM t"))
+	assert.Equal(t, n, ma.startQueryExecutionCount)
+}
+
+func TestConnection_QueryCost(t *testing.T) {
+	c := createConnectionFixture()
+	driverRows, err := c.QueryContext(context.Background(), "SELECTExecContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	rows := driverRows.(*Rows)
+	assert.Equal(t, int64(123), rows.DataScannedInBytes())
+	assert.Equal(t, CostUSD(123), rows.CostUSD())
+	assert.Nil(t, rows.Close())
+
+	c.connector.config.SetPricePerTB(6.75)
+	result, err := c.ExecContext(context.Background(), "SELECTExecContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(123), result.(AthenaResult).DataScannedInBytes())
+	assert.Equal(t, costUSD(123, 6.75), result.(AthenaResult).CostUSD())
+}
+
+func TestConnection_QueryContextOutputAccessDenied(t *testing.T) {
+	c := createConnectionFixture()
+	ma := c.athenaAPI.(*mockAthenaClient)
+
+	// no retry by default
+	ma.outputAccessDenied = 1
+	_, err := c.QueryContext(context.Background(), "SELECT_OUTPUT_ACCESS_DENIED", []driver.NamedValue{})
+	var queryErr *QueryError
+	assert.True(t, errors.As(err, &queryErr))
+	assert.Equal(t, "OUTPUT_ACCESS_DENIED_QID_1", queryErr.QueryID)
+	assert.Len(t, ma.clientRequestTokens, 1)
+
+	c.connector.config.SetOutputAccessDeniedRetries(2)
+	c.connector.config.SetOutputAccessDeniedRetryBackoff(time.Millisecond)
+	ma.outputAccessDenied = 2
+	ma.clientRequestTokens = nil
+	ctx := context.WithValue(context.Background(), IdempotencyKey, "job-1")
+	driverRows, err := c.QueryContext(ctx, "SELECT_OUTPUT_ACCESS_DENIED", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Equal(t, "PING_OK_QID", driverRows.(*Rows).QueryID())
+	assert.Len(t, ma.clientRequestTokens, 3)
+	// each retry is a new query execution
+	assert.NotEqual(t, ma.clientRequestTokens[0], ma.clientRequestTokens[1])
+	assert.NotEqual(t, ma.clientRequestTokens[1], ma.clientRequestTokens[2])
+	assert.Equal(t, *getClientRequestToken(ctx, "SELECT_OUTPUT_ACCESS_DENIED"), ma.clientRequestTokens[0])
+
+	// a persistent permission error
+	ma.outputAccessDenied = 5
+	ma.clientRequestTokens = nil
+	_, err = c.QueryContext(context.Background(), "SELECT_OUTPUT_ACCESS_DENIED", []driver.NamedValue{})
+	assert.True(t, errors.As(err, &queryErr))
+	assert.Equal(t, "OUTPUT_ACCESS_DENIED_QID_3", queryErr.QueryID)
+	assert.Contains(t, err.Error(), "after 2 retries, query IDs OUTPUT_ACCESS_DENIED_QID_1, "+
+		"OUTPUT_ACCESS_DENIED_QID_2, OUTPUT_ACCESS_DENIED_QID_3: query OUTPUT_ACCESS_DENIED_QID_3 failed: Access denied")
+	assert.Len(t, ma.clientRequestTokens, 3)
+	assert.Equal(t, []string{"", "", ""}, ma.clientRequestTokens)
+
+	c.connector.config.SetOutputAccessDeniedRetryBackoff(time.Hour)
+	ma.clientRequestTokens = nil
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = c.QueryContext(ctx, "SELECT_OUTPUT_ACCESS_DENIED", []driver.NamedValue{})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Len(t, ma.clientRequestTokens, 1)
+}
+
+func TestConnection_ExecContextRowsAffected(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+
+	result, err := c.ExecContext(context.Background(), "INSERT INTO t VALUES (1), (2), (3)", []driver.NamedValue{})
+	assert.Nil(t, err)
+	rowsAffected, err := result.RowsAffected()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), rowsAffected)
+
+	result, err = c.ExecContext(context.Background(), "CREATE TABLE t2 AS SELECT * FROM t1", []driver.NamedValue{})
+	assert.Nil(t, err)
+	rowsAffected, err = result.RowsAffected()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), rowsAffected)
+
+	result, err = c.ExecContext(context.Background(),
+		"MERGE INTO t USING s ON t.id = s.id WHEN MATCHED THEN DELETE", []driver.NamedValue{})
+	assert.Nil(t, err)
+	rowsAffected, err = result.RowsAffected()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), rowsAffected)
+
+	result, err = c.ExecContext(context.Background(), "CREATE EXTERNAL TABLE t (a int)", []driver.NamedValue{})
+	assert.Nil(t, err)
+	rowsAffected, err = result.RowsAffected()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), rowsAffected)
+}
+
+func TestConnection_UpdateResult(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+
+	result, err := c.ExecContext(context.Background(), "INSERT INTO t VALUES (1), (2), (3)", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Equal(t, UpdateResult{
+		QueryID:          "INSERT_3_ROWS_QID",
+		Has:              true,
+		UpdateCount:      3,
+		UpdateType:       "INSERT",
+		OutputLocation:   "s3://query-results-henry-wu-us-east-2/INSERT_3_ROWS_QID.csv",
+		ManifestLocation: "s3://query-results-henry-wu-us-east-2/INSERT_3_ROWS_QID-manifest.csv",
+	}, result.(AthenaResult).UpdateResult())
+
+	driverRows, err := c.QueryContext(context.Background(), "CREATE TABLE t2 AS SELECT * FROM t1",
+		[]driver.NamedValue{})
+	assert.Nil(t, err)
+	update := driverRows.(*Rows).UpdateResult()
+	assert.True(t, update.Has)
+	assert.Equal(t, int64(5), update.UpdateCount)
+	assert.Equal(t, "CREATE TABLE AS SELECT", update.UpdateType)
+	assert.Equal(t, "s3://query-results-henry-wu-us-east-2/CTAS_5_ROWS_QID-manifest.csv", update.ManifestLocation)
+
+	result, err = c.ExecContext(context.Background(),
+		"MERGE INTO t USING s ON t.id = s.id WHEN MATCHED THEN DELETE", []driver.NamedValue{})
+	assert.Nil(t, err)
+	update = result.(AthenaResult).UpdateResult()
+	assert.True(t, update.Has)
+	assert.Equal(t, "MERGE", update.UpdateType)
+	assert.Equal(t, "", update.ManifestLocation)
+
+	// neither for SELECT and DDL
+	result, err = c.ExecContext(context.Background(), "CREATE EXTERNAL TABLE t (a int)", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Equal(t, UpdateResult{QueryID: "DDL_QID"}, result.(AthenaResult).UpdateResult())
+	driverRows, err = c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	update = driverRows.(*Rows).UpdateResult()
+	assert.False(t, update.Has)
+	assert.Equal(t, int64(0), update.UpdateCount)
+	assert.Equal(t, "", update.UpdateType)
+	assert.Equal(t, "s3://query-results-henry-wu-us-east-2/SELECTQueryContext_OK_QID.csv", update.OutputLocation)
+}
+
+func TestConnection_QueryDateTimes(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+	submitted := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	completed := time.Date(2020, 1, 2, 3, 4, 12, 0, time.UTC)
+
+	result, err := c.ExecContext(context.Background(), "INSERT INTO t VALUES (1), (2), (3)", []driver.NamedValue{})
+	assert.Nil(t, err)
+	at, ok := result.(AthenaResult).SubmissionDateTime()
+	assert.True(t, ok)
+	assert.Equal(t, submitted, at)
+	at, ok = result.(AthenaResult).CompletionDateTime()
+	assert.True(t, ok)
+	assert.Equal(t, completed, at)
+
+	rows, err := c.QueryResultsByID(context.Background(), "INSERT_3_ROWS_QID")
+	assert.Nil(t, err)
+	at, ok = rows.SubmissionDateTime()
+	assert.True(t, ok)
+	assert.Equal(t, submitted, at)
+	at, ok = rows.CompletionDateTime()
+	assert.True(t, ok)
+	assert.Equal(t, completed, at)
+
+	// not reported
+	driverRows, err := c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	at, ok = driverRows.(*Rows).SubmissionDateTime()
+	assert.False(t, ok)
+	assert.True(t, at.IsZero())
+	_, ok = driverRows.(*Rows).CompletionDateTime()
+	assert.False(t, ok)
+}
+
+func TestConnection_ExecScript(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+
+	script := `CREATE EXTERNAL TABLE t (a int);
+INSERT INTO t VALUES (1), (2), (3);
+CREATE TABLE t2 AS SELECT * FROM t1; -- done; no more statements
+/* the end; */
+`
+	results, err := c.ExecScript(context.Background(), script)
+	assert.Nil(t, err)
+	assert.Len(t, results, 3)
+	rowsAffected, _ := results[1].RowsAffected()
+	assert.Equal(t, int64(3), rowsAffected)
+	rowsAffected, _ = results[2].RowsAffected()
+	assert.Equal(t, int64(5), rowsAffected)
+
+	// stop at the first failure
+	results, err = c.ExecScript(context.Background(),
+		"INSERT INTO t VALUES (1), (2), (3); StartQueryExecution_nil_error; CREATE EXTERNAL TABLE t (a int)")
+	assert.True(t, errors.Is(err, ErrTestMockGeneric))
+	assert.Equal(t, "statement 2 of 3 failed: "+ErrTestMockGeneric.Error(), err.Error())
+	assert.Len(t, results, 1)
+
+	results, err = c.ExecScript(context.Background(), "SELECT 'unterminated; SELECT 1")
+	assert.Nil(t, results)
+	assert.Equal(t, ErrInvalidQuery, err)
+}
+
+// fixtureConnector is a driver.Connector always returning the same Connection.
+type fixtureConnector struct {
+	conn *Connection
+}
+
+func (f fixtureConnector) Connect(context.Context) (driver.Conn, error) {
+	return f.conn, nil
+}
+
+func (f fixtureConnector) Driver() driver.Driver {
+	return &SQLDriver{}
+}
+
+func TestConnection_NoPrepareRoundTrip(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+	nm := c.athenaAPI.(*mockAthenaClient)
+	db := sql.OpenDB(fixtureConnector{conn: c})
+	defer db.Close()
+
+	rows, err := db.QueryContext(context.Background(), "SELECTQueryContext_OK")
+	assert.Nil(t, err)
+	assert.Nil(t, rows.Close())
+	assert.Equal(t, 1, nm.startQueryExecutionCount)
+
+	rows, err = db.QueryContext(context.Background(), "SELECTQueryContext_?", "OK")
+	assert.Nil(t, err)
+	assert.Nil(t, rows.Close())
+	assert.Equal(t, "SELECTQueryContext_'OK'", *nm.lastStartQueryExecutionInput.QueryString)
+	assert.Equal(t, 2, nm.startQueryExecutionCount)
+
+	result, err := db.ExecContext(context.Background(), "INSERT INTO t VALUES (1), (2), (3)")
+	assert.Nil(t, err)
+	rowsAffected, _ := result.RowsAffected()
+	assert.Equal(t, int64(3), rowsAffected)
+	assert.Equal(t, 3, nm.startQueryExecutionCount)
+}
+
+func TestConnection_QueryContextOutputLocation(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+
+	driverRows, err := c.QueryContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	location, ok := driverRows.(*Rows).OutputLocation()
+	assert.True(t, ok)
+	assert.Equal(t, "s3://query-results-henry-wu-us-east-2/SELECTQueryContext_OK_QID.csv", location)
+
+	driverRows, err = c.QueryContext(context.Background(), "SELECT 1", []driver.NamedValue{})
+	assert.Nil(t, err)
+	location, ok = driverRows.(*Rows).OutputLocation()
+	assert.False(t, ok)
+	assert.Equal(t, "", location)
+}
+
+func TestConnection_QueryContextBytesScannedCutoff(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+	c.athenaAPI.(*mockAthenaClient).GetWGStatus = true
+
+	driverRows, err := c.QueryContext(context.Background(), "SELECTQueryContext_BYTES_SCANNED_CUTOFF",
+		[]driver.NamedValue{})
+	assert.Nil(t, driverRows)
+	assert.True(t, errors.Is(err, ErrBytesScannedCutoff))
+	var cutoffErr *BytesScannedCutoffError
+	assert.True(t, errors.As(err, &cutoffErr))
+	assert.Equal(t, "SELECTQueryContext_BYTES_SCANNED_CUTOFF_QID", cutoffErr.QueryID)
+	assert.Equal(t, int64(DefaultBytesScannedCutoffPerQuery), cutoffErr.BytesScannedCutoff)
+	assert.Equal(t, int64(DefaultBytesScannedCutoffPerQuery+1), cutoffErr.DataScannedInBytes)
+
+	// Genuine cancellation is still reported as context.Canceled
+	driverRows, err = c.QueryContext(context.Background(), "SELECTQueryContext_AWS_CANCEL",
+		[]driver.NamedValue{})
+	assert.Nil(t, driverRows)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestConnection_QueryContextInsertFormat(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+
+	driverRows, err := c.QueryContext(context.Background(), `INSERT INTO "avro_db".events SELECT * FROM t`,
+		[]driver.NamedValue{})
+	assert.Nil(t, driverRows)
+	assert.True(t, errors.Is(err, ErrInsertFormatUnsupported))
+	var formatErr *InsertFormatError
+	assert.True(t, errors.As(err, &formatErr))
+	assert.Equal(t, "UNSUPPORTED_FORMAT_QID", formatErr.QueryID)
+	assert.Equal(t, "avro_db.events", formatErr.Table)
+	assert.Contains(t, err.Error(), "table avro_db.events")
+	assert.Contains(t, err.Error(), "CTAS")
+	assert.Contains(t, err.Error(), "HIVE_UNSUPPORTED_FORMAT")
+
+	// only INSERT failures are wrapped
+	driverRows, err = c.QueryContext(context.Background(), "SELECT_UNSUPPORTED_FORMAT", []driver.NamedValue{})
+	assert.Nil(t, driverRows)
+	assert.False(t, errors.Is(err, ErrInsertFormatUnsupported))
+	assert.Contains(t, err.Error(), "HIVE_UNSUPPORTED_FORMAT")
+	var queryErr *QueryError
+	assert.True(t, errors.As(err, &queryErr))
+	assert.Equal(t, "UNSUPPORTED_FORMAT_QID", queryErr.QueryID)
+	assert.True(t, queryErr.IsUserError())
+	assert.True(t, errors.Is(err, ErrQueryFailed))
+
+	assert.Equal(t, "query q failed to insert into the table, as Athena can't write to its storage format; "+
+		"use CREATE TABLE AS SELECT (CTAS) to write to a new table in a supported format like Parquet instead: r",
+		(&InsertFormatError{QueryID: "q", Reason: "r"}).Error())
+}
+
+func TestConnection_QueryContextResultFormat(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+	nm := c.athenaAPI.(*mockAthenaClient)
+
+	ctx := context.WithValue(context.Background(), ResultFormatKey, "PARQUET")
+	_, err := c.ExecContext(ctx, "CREATE TABLE t2 AS SELECT * FROM t1", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Equal(t, "CREATE TABLE t2 WITH (format = 'PARQUET') AS SELECT * FROM t1",
+		*nm.lastStartQueryExecutionInput.QueryString)
+
+	nm.startQueryExecutionCount = 0
+	driverRows, err := c.QueryContext(ctx, "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, driverRows)
+	assert.Equal(t, ErrResultFormatNotApplicable, err)
+	assert.Equal(t, 0, nm.startQueryExecutionCount)
+}
+
 func BenchmarkConnection_QueryContext(b *testing.B) {
 	for i := 0; i < 10000; i++ {
 		c := createConnectionFixture()
@@ -26,6 +26,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"os"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/uber-go/tally"
@@ -34,12 +35,32 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/glue"
+	"github.com/aws/aws-sdk-go/service/s3"
 )
 
 // SQLConnector is the connector for AWS Athena Driver.
 type SQLConnector struct {
 	config *Config
 	tracer *DriverTracer
+	// workgroups caches enabled workgroups got from Athena by name, as cachedWorkgroup.
+	workgroups sync.Map
+	// querySlots limits the queries running in Athena at the same time if MaxConcurrentQueries is set.
+	querySlots     chan struct{}
+	querySlotsOnce sync.Once
+	// sharedConfigRegion caches the region of AWS shared config file, which is only read once, as reading it
+	// creates an AWS session.
+	sharedConfigRegion     string
+	sharedConfigRegionOnce sync.Once
+}
+
+// NewSQLConnector is to create a SQLConnector with driver Config, which can be used with sql.OpenDB().
+// Unlike sql.Open() with DSN, it keeps the settings of Config not in DSN, like Logger.
+func NewSQLConnector(config *Config) *SQLConnector {
+	return &SQLConnector{
+		config: config,
+		tracer: newDefaultObservability(config),
+	}
 }
 
 // NoopsSQLConnector is to create a noops SQLConnector.
@@ -51,6 +72,39 @@ func NoopsSQLConnector() *SQLConnector {
 	}
 }
 
+// acquireQuerySlot is to wait for a slot to start a query if MaxConcurrentQueries is set, or until ctx is done.
+// The returned func releases the slot, and can be called more than once.
+func (c *SQLConnector) acquireQuerySlot(ctx context.Context) (func(), error) {
+	c.querySlotsOnce.Do(func() {
+		if n := c.config.GetMaxConcurrentQueries(); n > 0 {
+			c.querySlots = make(chan struct{}, n)
+		}
+	})
+	if c.querySlots == nil {
+		return func() {}, nil
+	}
+	select {
+	case c.querySlots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-c.querySlots })
+	}, nil
+}
+
+// getRegion is to get the region the same as Config.GetRegion, but reads AWS shared config file only once.
+func (c *SQLConnector) getRegion() string {
+	if region := c.config.getRegionFromDSNOrEnv(); region != "" {
+		return region
+	}
+	c.sharedConfigRegionOnce.Do(func() {
+		c.sharedConfigRegion = getSharedConfigRegion()
+	})
+	return c.sharedConfigRegion
+}
+
 // Driver is to construct a new SQLConnector.
 func (c *SQLConnector) Driver() driver.Driver {
 	return &SQLDriver{}
@@ -66,17 +120,24 @@ func (c *SQLConnector) Connect(ctx context.Context) (driver.Conn, error) {
 	if logger, ok := ctx.Value(LoggerKey).(*zap.Logger); ok {
 		c.tracer.SetLogger(logger)
 	}
+	region := c.getRegion()
+	if region == "" {
+		c.tracer.Scope().Counter(DriverName + ".failure.sqlconnector.region").Inc(1)
+		return nil, ErrConfigRegionNotFound
+	}
 	var awsAthenaSession *session.Session
 	var err error
 	// respect AWS_SDK_LOAD_CONFIG and local ~/.aws/credentials, ~/.aws/config
 	if ok, _ := strconv.ParseBool(os.Getenv("AWS_SDK_LOAD_CONFIG")); ok {
-		awsAthenaSession, err = session.NewSession(&aws.Config{})
+		awsAthenaSession, err = session.NewSession(&aws.Config{
+			Region: aws.String(region),
+		})
 	} else {
 		staticCredentials := credentials.NewStaticCredentials(c.config.GetAccessID(),
 			c.config.GetSecretAccessKey(),
 			c.config.GetSessionToken())
 		awsConfig := &aws.Config{
-			Region:      aws.String(c.config.GetRegion()),
+			Region:      aws.String(region),
 			Credentials: staticCredentials,
 		}
 		awsAthenaSession, err = session.NewSession(awsConfig)
@@ -85,11 +146,24 @@ func (c *SQLConnector) Connect(ctx context.Context) (driver.Conn, error) {
 		c.tracer.Scope().Counter(DriverName + ".failure.sqlconnector.newsession").Inc(1)
 		return nil, err
 	}
-	athenaAPI := athena.New(awsAthenaSession)
+	athenaConfig := &aws.Config{}
+	if endpoint := c.config.GetAthenaEndpoint(); endpoint != "" {
+		athenaConfig.Endpoint = aws.String(endpoint)
+	}
+	s3Config := &aws.Config{
+		S3ForcePathStyle: aws.Bool(c.config.IsS3ForcePathStyle()),
+	}
+	if endpoint := c.config.GetS3Endpoint(); endpoint != "" {
+		s3Config.Endpoint = aws.String(endpoint)
+	}
+	athenaAPI := athena.New(awsAthenaSession, athenaConfig)
 	timeConnect := time.Since(now)
 	conn := &Connection{
-		athenaAPI: athenaAPI,
-		connector: c,
+		athenaAPI:   athenaAPI,
+		s3API:       s3.New(awsAthenaSession, s3Config),
+		glueAPI:     glue.New(awsAthenaSession),
+		credentials: awsAthenaSession.Config.Credentials,
+		connector:   c,
 	}
 	c.tracer.Scope().Timer(DriverName + ".connector.connect").Record(timeConnect)
 	return conn, nil
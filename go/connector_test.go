@@ -22,10 +22,18 @@ package athenadriver
 
 import (
 	"context"
+	"database/sql/driver"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/stretchr/testify/assert"
 	"github.com/uber-go/tally"
 	"go.uber.org/zap"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -48,6 +56,35 @@ func TestSQLConnector(t *testing.T) {
 	assert.Equal(t, err.Error(), "Athena doesn't support transaction statements")
 }
 
+func TestSQLConnector_GetRegion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "athenadriver")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	configFile := filepath.Join(dir, "config")
+	assert.Nil(t, ioutil.WriteFile(configFile, []byte("[default]\nregion = eu-west-3\n"), 0600))
+	restore := setRegionEnv(t, map[string]string{
+		"AWS_CONFIG_FILE":             configFile,
+		"AWS_SHARED_CREDENTIALS_FILE": filepath.Join(dir, "credentials"),
+	})
+	defer restore()
+
+	testConf, err := NewConfig("s3://query-results-henry-wu-us-east-2?db=default")
+	assert.Nil(t, err)
+	connector := NewSQLConnector(testConf)
+	assert.Equal(t, "eu-west-3", connector.getRegion())
+
+	// the shared config file is read only once
+	assert.Nil(t, ioutil.WriteFile(configFile, []byte("[default]\nregion = us-west-1\n"), 0600))
+	assert.Equal(t, "us-west-1", testConf.GetRegion())
+	assert.Equal(t, "eu-west-3", connector.getRegion())
+
+	// DSN and environment variables still take precedence
+	os.Setenv("AWS_REGION", "us-west-2")
+	assert.Equal(t, "us-west-2", connector.getRegion())
+	_ = testConf.SetRegion("us-east-2")
+	assert.Equal(t, "us-east-2", connector.getRegion())
+}
+
 func TestSQLConnector_Connect(t *testing.T) {
 	testConf := NewNoOpsConfig()
 	connector := &SQLConnector{
@@ -120,6 +157,48 @@ func TestSQLConnector_Connect_NewSession_AWS_SDK_LOAD_CONFIG_false(t *testing.T)
 	assert.NotNil(t, conn)
 }
 
+func TestSQLConnector_Connect_RegionNotFound(t *testing.T) {
+	dir, err := ioutil.TempDir("", "athenadriver")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	restore := setRegionEnv(t, map[string]string{
+		"AWS_CONFIG_FILE":             filepath.Join(dir, "config"),
+		"AWS_SHARED_CREDENTIALS_FILE": filepath.Join(dir, "credentials"),
+	})
+	defer restore()
+	testConf, err := NewConfig("s3://query-results-henry-wu-us-east-2?db=default")
+	assert.Nil(t, err)
+	conn, err := NewSQLConnector(testConf).Connect(context.Background())
+	assert.Nil(t, conn)
+	assert.Equal(t, ErrConfigRegionNotFound, err)
+
+	os.Setenv("AWS_DEFAULT_REGION", "us-west-1")
+	conn, err = NewSQLConnector(testConf).Connect(context.Background())
+	assert.Nil(t, err)
+	assert.NotNil(t, conn)
+}
+
+func TestSQLConnector_Connect_Endpoints(t *testing.T) {
+	testConf := NewNoOpsConfig()
+	testConf.SetAthenaEndpoint("http://localhost:4566")
+	testConf.SetS3Endpoint("http://localhost:4567")
+	testConf.SetS3ForcePathStyle(true)
+	conn, err := NewSQLConnector(testConf).Connect(context.Background())
+	assert.Nil(t, err)
+	c := conn.(*Connection)
+	assert.Equal(t, "http://localhost:4566", c.athenaAPI.(*athena.Athena).Endpoint)
+	s3Client := c.s3API.(*s3.S3)
+	assert.Equal(t, "http://localhost:4567", s3Client.Endpoint)
+	assert.True(t, aws.BoolValue(s3Client.Config.S3ForcePathStyle))
+
+	// default endpoints
+	conn, err = NewSQLConnector(NewNoOpsConfig()).Connect(context.Background())
+	assert.Nil(t, err)
+	c = conn.(*Connection)
+	assert.Equal(t, "https://athena."+DefaultRegion+".amazonaws.com", c.athenaAPI.(*athena.Athena).Endpoint)
+	assert.False(t, aws.BoolValue(c.s3API.(*s3.S3).Config.S3ForcePathStyle))
+}
+
 func TestSQLConnector_Driver(t *testing.T) {
 	testConf := NewNoOpsConfig()
 	connector := &SQLConnector{
@@ -128,3 +207,63 @@ func TestSQLConnector_Driver(t *testing.T) {
 	}
 	assert.NotNil(t, connector.Driver())
 }
+
+func TestSQLConnector_AcquireQuerySlot(t *testing.T) {
+	// unlimited by default
+	connector := NoopsSQLConnector()
+	release, err := connector.acquireQuerySlot(context.Background())
+	assert.Nil(t, err)
+	release()
+	assert.Nil(t, connector.querySlots)
+
+	connector = NoopsSQLConnector()
+	connector.config.SetMaxConcurrentQueries(5)
+	var running, maxRunning int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := connector.acquireQuerySlot(context.Background())
+			assert.Nil(t, err)
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			release()
+			// releasing again is a no-op
+			release()
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, maxRunning, int32(5))
+	assert.Len(t, connector.querySlots, 0)
+}
+
+func TestConnection_QueryContextMaxConcurrentQueries(t *testing.T) {
+	c := createConnectionFixture()
+	c.connector.config.SetMaxConcurrentQueries(1)
+	release, err := c.connector.acquireQuerySlot(context.Background())
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = c.QueryContext(ctx, "SELECT 1", []driver.NamedValue{})
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	release()
+	driverRows, err := c.QueryContext(context.Background(), "SELECT 1", []driver.NamedValue{})
+	assert.Nil(t, err)
+	// the slot is released once the query finishes, before its rows are read
+	assert.Len(t, c.connector.querySlots, 0)
+	assert.Nil(t, driverRows.Close())
+
+	_, err = c.QueryContext(context.Background(), "SELECTQueryContext_AWS_FAIL", []driver.NamedValue{})
+	assert.NotNil(t, err)
+	assert.Len(t, c.connector.querySlots, 0)
+}
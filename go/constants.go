@@ -30,6 +30,13 @@ const (
 	// DefaultBytesScannedCutoffPerQuery is 1G for every user.
 	DefaultBytesScannedCutoffPerQuery = 1024 * 1024 * 1024
 
+	// DefaultPricePerTB is the price in USD per TB of data scanned by Athena in us-east-1.
+	// https://aws.amazon.com/athena/pricing/
+	DefaultPricePerTB = 5.0
+
+	// MinBytesBilled is the minimum data scanned billed for a query, which is 10MB.
+	MinBytesBilled = 10 * 1024 * 1024
+
 	// DefaultDBName is the default database name in Athena.
 	DefaultDBName = "default"
 
@@ -56,6 +63,24 @@ const (
 	// LoggerKey is the key for Logger in context
 	LoggerKey = TContextKey("LoggerKey")
 
+	// ClientRequestTokenKey is the key for the ClientRequestToken of StartQueryExecution in context.
+	// Athena dedupes queries submitted with the same token, which must be 32 to 128 characters long.
+	ClientRequestTokenKey = TContextKey("ClientRequestTokenKey")
+
+	// WorkgroupKey is the key in context for the workgroup name of a single query, which overrides
+	// the workgroup in driver Config.
+	WorkgroupKey = TContextKey("WorkgroupKey")
+
+	// IdempotencyKey is the key in context for a caller provided key of one logical query submission.
+	// If ClientRequestTokenKey is not set, a ClientRequestToken is derived from it and the query string,
+	// so retries of the same query get the same token, while different queries get different tokens.
+	IdempotencyKey = TContextKey("IdempotencyKey")
+
+	// ResultFormatKey is the key in context for the result format of a single CTAS or UNLOAD statement, one of
+	// CSV, TSV, PARQUET, ORC, JSON and AVRO. It is set in the WITH clause of the statement, and it is an
+	// error to set it for other statements.
+	ResultFormatKey = TContextKey("ResultFormatKey")
+
 	// DummyRegion is used when AWS CLI Config is used, ie AWS_SDK_LOAD_CONFIG is set
 	DummyRegion = "dummy"
 
@@ -77,11 +102,16 @@ const (
 	// PoolInterval is the interval between two status checks(unit second).
 	PoolInterval = 3
 
+	// OutputAccessDeniedRetryBackoff is the default backoff before the first retry of a query failed with
+	// S3 access denied on its output location(unit second).
+	OutputAccessDeniedRetryBackoff = 1
+
+	// CredentialsExpiryWindow is how long before its AWS credentials expire a pooled connection
+	// is recycled by default(unit second).
+	CredentialsExpiryWindow = 5 * 60
+
 	// The maximum allowed query string length is 262144 bytes,
 	// where the strings are encoded in UTF-8.
 	// This is not an adjustable quota. (unit bytes)
 	MAXQueryStringLength = 262144
 )
-
-const digits01 = "0123456789012345678901234567890123456789012345678901234567890123456789012345678901234567890123456789"
-const digits10 = "0000000000111111111122222222223333333333444444444455555555556666666666777777777788888888889999999999"
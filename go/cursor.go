@@ -0,0 +1,145 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package athenadriver
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/athena"
+)
+
+// cursor is the position of a page in the results of a query.
+type cursor struct {
+	QueryID   string `json:"q"`
+	NextToken string `json:"t,omitempty"`
+}
+
+// NewCursor is to create an opaque cursor pointing to the first page of the results of a query.
+func NewCursor(queryExecutionID string) string {
+	return encodeCursor(queryExecutionID, "")
+}
+
+func encodeCursor(queryID string, nextToken string) string {
+	b, _ := json.Marshal(cursor{QueryID: queryID, NextToken: nextToken})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err = json.Unmarshal(b, &c); err != nil || c.QueryID == "" {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// FetchPage is to fetch one page of query results at a cursor, which is created by NewCursor or returned
+// by the previous FetchPage, possibly in a different process. The returned Rows only contains the rows of
+// that page, and the returned cursor points to the next page. It is empty if there is no more page.
+// As Athena query results expire eventually, ErrInvalidCursor is returned if Athena rejects the cursor.
+func (c *Connection) FetchPage(ctx context.Context, cur string) (*Rows, string, error) {
+	pos, err := decodeCursor(cur)
+	if err != nil {
+		return nil, "", err
+	}
+	r := Rows{
+		athena:     c.athenaAPI,
+		ctx:        ctx,
+		queryID:    pos.QueryID,
+		config:     c.connector.config,
+		tracer:     c.connector.tracer,
+		pageCount:  -1,
+		singlePage: true,
+	}
+	var token *string
+	if pos.NextToken != "" {
+		token = &pos.NextToken
+		// the header row only appears in the first page
+		r.pageCount = 0
+	}
+	if err := r.fetchNextPage(token); err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == athena.ErrCodeInvalidRequestException {
+			return nil, "", fmt.Errorf("%w: %s", ErrInvalidCursor, aerr.Message())
+		}
+		return nil, "", err
+	}
+	nextCursor := ""
+	if r.ResultOutput.NextToken != nil && *r.ResultOutput.NextToken != "" {
+		nextCursor = encodeCursor(pos.QueryID, *r.ResultOutput.NextToken)
+	}
+	return &r, nextCursor, nil
+}
+
+// QueryResultsByID is to fetch the results of a previous query by its QueryExecutionId without running it
+// again, so a query can be submitted by one service and its results consumed by another. All pages of the
+// results are read and converted like those of a normal query. It returns a *QueryError if the query failed,
+// an error wrapping ErrQueryCancelled if it was cancelled, ErrQueryNotFinished if it is still QUEUED or RUNNING,
+// and ErrQueryResultsExpired if Athena no longer has its results. As database/sql can't wrap a driver.Rows
+// into sql.Rows, use it with sql.Conn.Raw() of Go 1.14+ like:
+//   conn.Raw(func(driverConn interface{}) error {
+//       rows, err := driverConn.(*athenadriver.Connection).QueryResultsByID(ctx, queryID)
+//       ...
+//   })
+func (c *Connection) QueryResultsByID(ctx context.Context, queryID string) (*Rows, error) {
+	statusResp, err := c.athenaAPI.GetQueryExecutionWithContext(ctx, &athena.GetQueryExecutionInput{
+		QueryExecutionId: aws.String(queryID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	qe := statusResp.QueryExecution
+	if qe == nil || qe.Status == nil {
+		return nil, fmt.Errorf("%w: query %s has no status", ErrQueryNotFinished, queryID)
+	}
+	reason := aws.StringValue(qe.Status.StateChangeReason)
+	switch state := aws.StringValue(qe.Status.State); state {
+	case athena.QueryExecutionStateSucceeded:
+	case athena.QueryExecutionStateFailed:
+		return nil, newQueryError(queryID, qe.Status)
+	case athena.QueryExecutionStateCancelled:
+		return nil, fmt.Errorf("%w: query %s: %s", ErrQueryCancelled, queryID, reason)
+	default:
+		return nil, fmt.Errorf("%w: query %s is %s", ErrQueryNotFinished, queryID, state)
+	}
+
+	rows, err := NewRows(ctx, c.athenaAPI, queryID, c.connector.config, c.connector.tracer)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == athena.ErrCodeInvalidRequestException {
+			return nil, fmt.Errorf("%w: query %s: %s", ErrQueryResultsExpired, queryID, aerr.Message())
+		}
+		return nil, err
+	}
+	if qe.ResultConfiguration != nil {
+		rows.outputLocation = qe.ResultConfiguration.OutputLocation
+	}
+	rows.submitted = qe.Status.SubmissionDateTime
+	rows.completed = qe.Status.CompletionDateTime
+	rows.glueAPI = c.glueAPI
+	return rows, nil
+}
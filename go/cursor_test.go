@@ -0,0 +1,108 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package athenadriver
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursor(t *testing.T) {
+	c, err := decodeCursor(NewCursor("abc"))
+	assert.Nil(t, err)
+	assert.Equal(t, cursor{QueryID: "abc"}, c)
+
+	c, err = decodeCursor(encodeCursor("abc", "a1"))
+	assert.Nil(t, err)
+	assert.Equal(t, cursor{QueryID: "abc", NextToken: "a1"}, c)
+
+	_, err = decodeCursor("not base64!")
+	assert.Equal(t, ErrInvalidCursor, err)
+	_, err = decodeCursor("")
+	assert.Equal(t, ErrInvalidCursor, err)
+}
+
+func TestConnection_FetchPage(t *testing.T) {
+	c := createConnectionFixture()
+	cur := NewCursor("SELECT_OK")
+	var pageSizes []int
+	for cur != "" {
+		rows, next, err := c.FetchPage(context.Background(), cur)
+		assert.Nil(t, err)
+		assert.Equal(t, "SELECT_OK", rows.QueryID())
+		dest := make([]driver.Value, len(rows.Columns()))
+		cnt := 0
+		for rows.Next(dest) != io.EOF {
+			cnt++
+		}
+		pageSizes = append(pageSizes, cnt)
+		cur = next
+	}
+	// the header row of the first page is skipped
+	assert.Equal(t, []int{5, 10, 5, 5, 10}, pageSizes)
+
+	_, _, err := c.FetchPage(context.Background(), "bad cursor")
+	assert.Equal(t, ErrInvalidCursor, err)
+
+	_, _, err = c.FetchPage(context.Background(),
+		encodeCursor("SELECT_OK", "GetQueryResultsWithContext_return_expired"))
+	assert.True(t, errors.Is(err, ErrInvalidCursor))
+
+	_, _, err = c.FetchPage(context.Background(),
+		encodeCursor("SELECT_OK", "GetQueryResultsWithContext_return_error"))
+	assert.Equal(t, ErrTestMockGeneric, err)
+}
+
+func TestConnection_QueryResultsByID(t *testing.T) {
+	c := createConnectionFixture()
+	rows, err := c.QueryResultsByID(context.Background(), "NULL_MIXED_QID")
+	assert.Nil(t, err)
+	assert.Equal(t, "NULL_MIXED_QID", rows.QueryID())
+	assert.Equal(t, []string{"i", "f", "b", "t", "s"}, rows.Columns())
+	dest := make([]driver.Value, len(rows.Columns()))
+	assert.Nil(t, rows.Next(dest))
+	assert.Nil(t, rows.Close())
+
+	_, err = c.QueryResultsByID(context.Background(), "SELECTQueryContext_AWS_FAIL_QID")
+	assert.True(t, errors.Is(err, ErrQueryFailed))
+	assert.Contains(t, err.Error(), "something_broken")
+	var queryErr *QueryError
+	assert.True(t, errors.As(err, &queryErr))
+	assert.Equal(t, "SELECTQueryContext_AWS_FAIL_QID", queryErr.QueryID)
+
+	_, err = c.QueryResultsByID(context.Background(), "SELECTQueryContext_AWS_CANCEL_QID")
+	assert.True(t, errors.Is(err, ErrQueryCancelled))
+
+	_, err = c.QueryResultsByID(context.Background(), "SELECTQueryContext_CANCEL_OK_QID")
+	assert.True(t, errors.Is(err, ErrQueryNotFinished))
+	assert.Contains(t, err.Error(), "QUEUED")
+
+	_, err = c.QueryResultsByID(context.Background(), "EXPIRED_RESULTS_QID")
+	assert.True(t, errors.Is(err, ErrQueryResultsExpired))
+
+	_, err = c.QueryResultsByID(context.Background(), "QueryExecutionStateFailed_QID")
+	assert.Equal(t, ErrTestMockFailedByAthena, err)
+}
@@ -22,6 +22,10 @@ package athenadriver
 
 import (
 	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
 )
 
 // Various errors the driver might return. Can change between driver versions.
@@ -29,16 +33,136 @@ var (
 	ErrInvalidQuery                 = errors.New("query is not valid")
 	ErrConfigInvalidConfig          = errors.New("driver config is invalid")
 	ErrConfigOutputLocation         = errors.New("output location must starts with s3")
+	ErrConfigOutputLocationNotFound = errors.New("output location is not found in DSN or workgroup")
+	ErrConfigOutputPrefixTemplate   = errors.New("output prefix template must expand to a valid S3 key prefix")
+	ErrConfigAclOption              = errors.New("ACL option must be BUCKET_OWNER_FULL_CONTROL")
 	ErrConfigRegion                 = errors.New("region is required")
+	ErrConfigRegionNotFound         = errors.New("region is not found in DSN, AWS_REGION, AWS_DEFAULT_REGION or AWS shared config")
 	ErrConfigWGPointer              = errors.New("workgroup pointer is nil")
+	ErrConfigQueryTimeout           = errors.New("query timeout must be positive")
 	ErrConfigAccessIDRequired       = errors.New("AWS access ID is required")
 	ErrConfigAccessKeyRequired      = errors.New("AWS access Key is required")
 	ErrQueryUnknownType             = errors.New("query parameter type is unknown")
 	ErrQueryBufferOF                = errors.New("query buffer overflow")
 	ErrQueryTimeout                 = errors.New("query timeout")
+	ErrQueueTimeout                 = errors.New("query timeout in QUEUED state")
+	ErrScanLimitExceeded            = errors.New("query exceeded the max scanned bytes")
+	ErrReadOnly                     = errors.New("writing to Athena database is disallowed in read-only mode")
 	ErrAthenaTransactionUnsupported = errors.New("Athena doesn't support transaction statements")
 	ErrAthenaNilDatum               = errors.New("*athena.Datum must not be nil")
 	ErrAthenaNilAPI                 = errors.New("athenaAPI must not be nil")
+	ErrBytesScannedCutoff           = errors.New("query exceeded the workgroup bytes scanned cutoff")
+	ErrInsertFormatUnsupported      = errors.New("Athena can't insert into tables of this storage format")
+	ErrInvalidResultFormat          = errors.New("result format must be one of CSV, TSV, PARQUET, ORC, JSON and AVRO")
+	ErrResultFormatNotApplicable    = errors.New("result format only applies to CTAS and UNLOAD statements")
+	ErrResultFormatConflict         = errors.New("result format conflicts with the format in the query")
+	ErrInvalidCursor                = errors.New("cursor is invalid or its query results have expired")
+	ErrQueryFailed                  = errors.New("query failed")
+	ErrQueryCancelled               = errors.New("query was cancelled")
+	ErrQueryNotFinished             = errors.New("query has not finished yet")
+	ErrQueryResultsExpired          = errors.New("query results have expired")
+	ErrInvalidIPAddress             = errors.New("invalid IP address")
+	ErrIntervalYearToMonth          = errors.New("interval year to month can't be converted to time.Duration")
+	ErrInvalidWorkgroupName         = errors.New("workgroup name must be 1 to 128 characters of a-z, A-Z, 0-9, _, . or -")
+	ErrEngineVersionMismatch        = errors.New("workgroup engine version is not the configured engine version")
 	ErrTestMockGeneric              = errors.New("some_mock_error_for_test")
 	ErrTestMockFailedByAthena       = errors.New("the reason why Athena failed the query")
 )
+
+// The categories of a failed query, the same as ErrorCategory of AthenaError in the Athena API.
+// https://docs.aws.amazon.com/athena/latest/APIReference/API_AthenaError.html
+const (
+	ErrorCategorySystem int64 = 1
+	ErrorCategoryUser   int64 = 2
+	ErrorCategoryOther  int64 = 3
+)
+
+// QueryError is returned when Athena fails a query. It unwraps to ErrQueryFailed.
+//
+// Category and ErrorType are from the AthenaError of the query status. Athena doesn't report AthenaError for
+// some failures, then Category is inferred from the error name at the beginning of StateChangeReason, like
+// SYNTAX_ERROR for ErrorCategoryUser, and ErrorType is 0.
+// https://docs.aws.amazon.com/athena/latest/ug/error-reference.html
+type QueryError struct {
+	QueryID   string
+	Category  int64
+	ErrorType int64
+	Reason    string
+}
+
+// newQueryError is to create a QueryError of queryID from its status.
+func newQueryError(queryID string, status *athena.QueryExecutionStatus) *QueryError {
+	reason := aws.StringValue(status.StateChangeReason)
+	if status.AthenaError != nil && aws.Int64Value(status.AthenaError.ErrorCategory) != 0 {
+		return &QueryError{QueryID: queryID, Category: *status.AthenaError.ErrorCategory,
+			ErrorType: aws.Int64Value(status.AthenaError.ErrorType), Reason: reason}
+	}
+	return &QueryError{QueryID: queryID, Category: errorCategory(reason), Reason: reason}
+}
+
+// Error is to implement interface error.
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("query %s failed: %s", e.QueryID, e.Reason)
+}
+
+// Unwrap returns ErrQueryFailed.
+func (e *QueryError) Unwrap() error {
+	return ErrQueryFailed
+}
+
+// IsUserError is to check if the query failed due to the query itself, like bad SQL or a missing table,
+// so retrying it won't help.
+func (e *QueryError) IsUserError() bool {
+	return e.Category == ErrorCategoryUser
+}
+
+// IsSystemError is to check if the query failed due to Athena, so it can be retried.
+func (e *QueryError) IsSystemError() bool {
+	return e.Category == ErrorCategorySystem
+}
+
+// BytesScannedCutoffError is returned when Athena terminates a query because it
+// scanned more data than the per-query limit of its workgroup allows.
+// It unwraps to ErrBytesScannedCutoff, so errors.Is can be used to tell a policy
+// cutoff apart from a genuine query failure.
+type BytesScannedCutoffError struct {
+	QueryID            string
+	BytesScannedCutoff int64 // 0 if the workgroup limit couldn't be retrieved
+	DataScannedInBytes int64
+	Reason             string
+}
+
+// Error is to implement interface error.
+func (e *BytesScannedCutoffError) Error() string {
+	return fmt.Sprintf("query %s was terminated after scanning %d bytes, workgroup bytes scanned cutoff is %d: %s",
+		e.QueryID, e.DataScannedInBytes, e.BytesScannedCutoff, e.Reason)
+}
+
+// Unwrap returns ErrBytesScannedCutoff.
+func (e *BytesScannedCutoffError) Unwrap() error {
+	return ErrBytesScannedCutoff
+}
+
+// InsertFormatError is returned when Athena fails an INSERT because it can't write to the storage format
+// of the table, like some Avro tables. It unwraps to ErrInsertFormatUnsupported.
+type InsertFormatError struct {
+	QueryID string
+	Table   string // empty if it couldn't be parsed from the query
+	Reason  string
+}
+
+// Error is to implement interface error.
+func (e *InsertFormatError) Error() string {
+	table := "the table"
+	if e.Table != "" {
+		table = "table " + e.Table
+	}
+	return fmt.Sprintf("query %s failed to insert into %s, as Athena can't write to its storage format; "+
+		"use CREATE TABLE AS SELECT (CTAS) to write to a new table in a supported format like Parquet instead: %s",
+		e.QueryID, table, e.Reason)
+}
+
+// Unwrap returns ErrInsertFormatUnsupported.
+func (e *InsertFormatError) Unwrap() error {
+	return ErrInsertFormatUnsupported
+}
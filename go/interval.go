@@ -0,0 +1,196 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package athenadriver
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Interval represents an Athena interval year to month or interval day to second value, which Athena
+// returns as strings like `-1-2` or `2 03:04:05.678`. A negative interval has all fields negative or zero.
+type Interval struct {
+	Months int64 // the year to month part, like 14 for `1-2`
+	Days   int64 // the day part of day to second
+	Nanos  int64 // the time part of day to second, less than a day
+	Valid  bool  // Valid is false if the value is NULL
+}
+
+// Scan is to implement interface sql.Scanner.
+func (i *Interval) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*i = Interval{}
+		return nil
+	case []byte:
+		iv, _, err := parseInterval(string(v))
+		if err != nil {
+			return err
+		}
+		*i = iv
+		return nil
+	case string:
+		iv, _, err := parseInterval(v)
+		if err != nil {
+			return err
+		}
+		*i = iv
+		return nil
+	default:
+		return fmt.Errorf("cannot convert %v (%T) to interval", src, src)
+	}
+}
+
+// Duration is to convert an interval day to second to time.Duration.
+// ErrIntervalYearToMonth is returned for an interval year to month, as months are not of fixed length.
+func (i Interval) Duration() (time.Duration, error) {
+	if i.Months != 0 {
+		return 0, ErrIntervalYearToMonth
+	}
+	return time.Duration(i.Days)*24*time.Hour + time.Duration(i.Nanos), nil
+}
+
+// String returns the interval in the format of Athena, which is year to month if Months is not 0.
+func (i Interval) String() string {
+	sign := ""
+	if i.Months < 0 || i.Days < 0 || i.Nanos < 0 {
+		sign = "-"
+	}
+	if i.Months != 0 {
+		m := abs64(i.Months)
+		return fmt.Sprintf("%s%d-%d", sign, m/12, m%12)
+	}
+	d := time.Duration(abs64(i.Nanos))
+	h := d / time.Hour
+	d -= h * time.Hour
+	m := d / time.Minute
+	d -= m * time.Minute
+	s := d / time.Second
+	d -= s * time.Second
+	return fmt.Sprintf("%s%d %02d:%02d:%02d.%03d", sign, abs64(i.Days), h, m, s, d/time.Millisecond)
+}
+
+// durationScanner is a sql.Scanner to convert an Athena interval day to second column into time.Duration.
+type durationScanner struct {
+	target *time.Duration
+}
+
+// ScanDuration is to create a sql.Scanner which converts an Athena interval day to second column into
+// target. NULL leaves target untouched. ErrIntervalYearToMonth is returned for an interval year to month.
+// Example:
+//   var d time.Duration
+//   err := rows.Scan(athenadriver.ScanDuration(&d))
+func ScanDuration(target *time.Duration) sql.Scanner {
+	return durationScanner{target: target}
+}
+
+// Scan is to implement interface sql.Scanner.
+func (d durationScanner) Scan(src interface{}) error {
+	s, ok, err := scannedString(src, "time.Duration")
+	if !ok || err != nil {
+		return err
+	}
+	iv, yearToMonth, err := parseInterval(s)
+	if err != nil {
+		return err
+	}
+	if yearToMonth {
+		return ErrIntervalYearToMonth
+	}
+	*d.target, err = iv.Duration()
+	return err
+}
+
+// parseInterval is to parse an Athena interval, like `-1-2` for year to month, or `2 03:04:05.678`
+// for day to second. The bool is true for year to month.
+func parseInterval(v string) (Interval, bool, error) {
+	s := strings.TrimSpace(v)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	invalid := fmt.Errorf("cannot convert %q to interval", v)
+	if s == "" {
+		return Interval{}, false, invalid
+	}
+	if !strings.ContainsAny(s, " :") {
+		parts := strings.Split(s, "-")
+		if len(parts) != 2 {
+			return Interval{}, false, invalid
+		}
+		y, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return Interval{}, false, invalid
+		}
+		m, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || m < 0 || m >= 12 {
+			return Interval{}, false, invalid
+		}
+		iv := Interval{Months: y*12 + m, Valid: true}
+		if negative {
+			iv.Months = -iv.Months
+		}
+		return iv, true, nil
+	}
+
+	parts := strings.Fields(s)
+	if len(parts) != 2 {
+		return Interval{}, false, invalid
+	}
+	days, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || days < 0 {
+		return Interval{}, false, invalid
+	}
+	hms := strings.Split(parts[1], ":")
+	if len(hms) != 3 {
+		return Interval{}, false, invalid
+	}
+	h, err := strconv.ParseInt(hms[0], 10, 64)
+	if err != nil || h < 0 || h >= 24 {
+		return Interval{}, false, invalid
+	}
+	m, err := strconv.ParseInt(hms[1], 10, 64)
+	if err != nil || m < 0 || m >= 60 {
+		return Interval{}, false, invalid
+	}
+	sec, err := strconv.ParseFloat(hms[2], 64)
+	if err != nil || sec < 0 || sec >= 60 {
+		return Interval{}, false, invalid
+	}
+	iv := Interval{
+		Days:  days,
+		Nanos: int64(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(math.Round(sec*1e9))),
+		Valid: true,
+	}
+	if negative {
+		iv.Days, iv.Nanos = -iv.Days, -iv.Nanos
+	}
+	return iv, false, nil
+}
+
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
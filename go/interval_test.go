@@ -0,0 +1,109 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package athenadriver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterval_Scan(t *testing.T) {
+	tests := []struct {
+		src      interface{}
+		expected Interval
+	}{
+		{"1-2", Interval{Months: 14, Valid: true}},
+		{"-1-2", Interval{Months: -14, Valid: true}},
+		{"0-0", Interval{Valid: true}},
+		{[]byte("2 03:04:05.678"), Interval{Days: 2,
+			Nanos: int64(3*time.Hour + 4*time.Minute + 5678*time.Millisecond), Valid: true}},
+		{"-2 03:04:05.678", Interval{Days: -2,
+			Nanos: -int64(3*time.Hour + 4*time.Minute + 5678*time.Millisecond), Valid: true}},
+		{"0 00:00:00.001", Interval{Nanos: int64(time.Millisecond), Valid: true}},
+		{nil, Interval{}},
+	}
+	for _, test := range tests {
+		iv := Interval{Months: 1, Valid: true}
+		assert.Nil(t, iv.Scan(test.src))
+		assert.Equal(t, test.expected, iv)
+	}
+
+	for _, src := range []interface{}{"", "-", "1", "1-12", "1-2-3", "a-b", "1 2:3", "1 24:00:00.000",
+		"-1 -1:00:00.000", "1 00:60:00.000", 1} {
+		var iv Interval
+		assert.NotNil(t, iv.Scan(src), src)
+	}
+}
+
+func TestInterval_RoundTrip(t *testing.T) {
+	for _, s := range []string{"1-2", "-1-2", "100-11", "2 03:04:05.678", "-2 03:04:05.678",
+		"0 00:00:00.000", "-0 23:59:59.999"} {
+		var iv Interval
+		assert.Nil(t, iv.Scan(s))
+		assert.Equal(t, s, iv.String())
+	}
+
+	for i := 0; i < 100; i++ {
+		for _, s := range []*string{randIntervalYearToMonth(), randIntervalDayToSecond()} {
+			var iv Interval
+			assert.Nil(t, iv.Scan(*s))
+			assert.Equal(t, *s, iv.String())
+		}
+	}
+}
+
+func TestInterval_Duration(t *testing.T) {
+	d, err := Interval{Days: -1, Nanos: -int64(time.Hour)}.Duration()
+	assert.Nil(t, err)
+	assert.Equal(t, -25*time.Hour, d)
+
+	_, err = Interval{Months: 1}.Duration()
+	assert.Equal(t, ErrIntervalYearToMonth, err)
+}
+
+func TestScanDuration(t *testing.T) {
+	var d time.Duration
+	assert.Nil(t, ScanDuration(&d).Scan("1 01:00:00.500"))
+	assert.Equal(t, 25*time.Hour+500*time.Millisecond, d)
+	assert.Nil(t, ScanDuration(&d).Scan([]byte("-0 00:00:01.000")))
+	assert.Equal(t, -time.Second, d)
+	assert.Nil(t, ScanDuration(&d).Scan(nil))
+	assert.Equal(t, -time.Second, d)
+
+	// year to month can't be converted even if it is 0
+	assert.Equal(t, ErrIntervalYearToMonth, ScanDuration(&d).Scan("0-0"))
+	assert.Equal(t, ErrIntervalYearToMonth, ScanDuration(&d).Scan("1-2"))
+	assert.NotNil(t, ScanDuration(&d).Scan("x"))
+	assert.NotNil(t, ScanDuration(&d).Scan(1))
+}
+
+func TestInterval_Rows(t *testing.T) {
+	row := randRow([]*athena.ColumnInfo{newColumnInfo("ym", "interval year to month"),
+		newColumnInfo("ds", "interval day to second")})
+	var ym Interval
+	assert.Nil(t, ym.Scan(*row.Data[0].VarCharValue))
+	var d time.Duration
+	assert.Nil(t, ScanDuration(&d).Scan(*row.Data[1].VarCharValue))
+	assert.Equal(t, ErrIntervalYearToMonth, ScanDuration(&d).Scan(*row.Data[0].VarCharValue))
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package athenadriver
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+)
+
+// ipScanner is a sql.Scanner to convert an Athena ipaddress column into net.IP.
+type ipScanner struct {
+	target *net.IP
+}
+
+// ScanIP is to create a sql.Scanner which converts an Athena ipaddress column, in IPv4 or IPv6 textual form,
+// into target. NULL leaves target untouched. An error wrapping ErrInvalidIPAddress is returned for an invalid
+// address, which database/sql reports with the column name.
+// Example:
+//   var ip net.IP
+//   err := rows.Scan(athenadriver.ScanIP(&ip))
+func ScanIP(target *net.IP) sql.Scanner {
+	return ipScanner{target: target}
+}
+
+// Scan is to implement interface sql.Scanner.
+func (i ipScanner) Scan(src interface{}) error {
+	s, ok, err := scannedString(src, "net.IP")
+	if !ok || err != nil {
+		return err
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return fmt.Errorf("%w: %q", ErrInvalidIPAddress, s)
+	}
+	*i.target = ip
+	return nil
+}
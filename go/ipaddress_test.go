@@ -0,0 +1,73 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package athenadriver
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanIP(t *testing.T) {
+	var ip net.IP
+	assert.Nil(t, ScanIP(&ip).Scan("192.168.0.1"))
+	assert.Equal(t, net.ParseIP("192.168.0.1"), ip)
+	assert.Nil(t, ScanIP(&ip).Scan([]byte("2001:db8::ff00:42:8329")))
+	assert.Equal(t, "2001:db8::ff00:42:8329", ip.String())
+
+	// NULL leaves target untouched
+	assert.Nil(t, ScanIP(&ip).Scan(nil))
+	assert.Equal(t, "2001:db8::ff00:42:8329", ip.String())
+
+	for _, src := range []interface{}{"", "256.0.0.1", "192.168.0", "2001:db8::g", "localhost"} {
+		err := ScanIP(&ip).Scan(src)
+		assert.True(t, errors.Is(err, ErrInvalidIPAddress), src)
+	}
+	assert.NotNil(t, ScanIP(&ip).Scan(1))
+	assert.Equal(t, "2001:db8::ff00:42:8329", ip.String())
+}
+
+func TestScanIP_Rows(t *testing.T) {
+	sqlRows := sqlmock.NewRows([]string{"src", "dst"})
+	sqlRows.AddRow("10.0.0.1", "::1")
+	sqlRows.AddRow("10.0.0.2", "not an ip")
+	rows := mockRowsToSQLRows(sqlRows)
+	var src, dst net.IP
+	assert.True(t, rows.Next())
+	assert.Nil(t, rows.Scan(ScanIP(&src), ScanIP(&dst)))
+	assert.Equal(t, "10.0.0.1", src.String())
+	assert.Equal(t, net.IPv6loopback, dst)
+
+	assert.True(t, rows.Next())
+	err := rows.Scan(ScanIP(&src), ScanIP(&dst))
+	assert.True(t, errors.Is(err, ErrInvalidIPAddress))
+	assert.Contains(t, err.Error(), `name "dst"`)
+}
+
+func TestRandIPAddress(t *testing.T) {
+	var ip net.IP
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, ScanIP(&ip).Scan(*randIPAddress()))
+	}
+}
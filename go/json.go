@@ -0,0 +1,48 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package athenadriver
+
+import (
+	"database/sql"
+	"encoding/json"
+)
+
+// jsonScanner is a sql.Scanner to unmarshal an Athena json column into target.
+type jsonScanner struct {
+	target interface{}
+}
+
+// ScanJSON is to create a sql.Scanner which unmarshals an Athena json column into target, which should be
+// a pointer like *struct or *map[string]interface{}. NULL leaves target untouched. Example:
+//   var s struct{ Name string `json:"name"` }
+//   err := rows.Scan(athenadriver.ScanJSON(&s))
+func ScanJSON(target interface{}) sql.Scanner {
+	return jsonScanner{target: target}
+}
+
+// Scan is to implement interface sql.Scanner.
+func (j jsonScanner) Scan(src interface{}) error {
+	s, ok, err := scannedString(src, "json")
+	if !ok || err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(s), j.target)
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package athenadriver
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanJSON(t *testing.T) {
+	raw := `{"name":"a,b","tags":["x\ny"]}`
+	var s struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+	assert.Nil(t, ScanJSON(&s).Scan([]byte(raw)))
+	assert.Equal(t, "a,b", s.Name)
+	assert.Equal(t, []string{"x\ny"}, s.Tags)
+
+	var m map[string]interface{}
+	assert.Nil(t, ScanJSON(&m).Scan(raw))
+	assert.Equal(t, "a,b", m["name"])
+	assert.Nil(t, ScanJSON(&m).Scan(nil))
+	assert.NotNil(t, ScanJSON(&m).Scan(1))
+	assert.NotNil(t, ScanJSON(&m).Scan([]byte("{")))
+}
+
+func TestScanJSONColumn(t *testing.T) {
+	raw := `{"name":"a,b","tags":["x\ny"]}`
+	sqlRows := sqlmock.NewRows([]string{"j1", "j2"})
+	sqlRows.AddRow([]byte(raw), []byte(raw))
+	rows := mockRowsToSQLRows(sqlRows)
+	defer rows.Close()
+	var rawMessage json.RawMessage
+	var s struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+	assert.True(t, rows.Next())
+	assert.Nil(t, rows.Scan(&rawMessage, ScanJSON(&s)))
+	assert.Equal(t, raw, string(rawMessage))
+	assert.Equal(t, "a,b", s.Name)
+	assert.Equal(t, []string{"x\ny"}, s.Tags)
+}
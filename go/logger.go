@@ -0,0 +1,131 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package athenadriver
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"go.uber.org/zap"
+)
+
+// Logger is the interface for the driver's own output, like the query cost in moneywise mode and
+// query lifecycle messages. Unlike the zap.Logger in DriverTracer, it is set in Config with SetLogger,
+// so the output can be routed to zap, logrus or any other logging library.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// FieldLogger is an optional interface of Logger to attach a key-value field to all its messages.
+// The driver uses it to log with the QueryExecutionId of a query as field "queryID".
+type FieldLogger interface {
+	Logger
+	With(key string, value interface{}) Logger
+}
+
+// withField is to attach a field to Logger if it is a FieldLogger, otherwise Logger is returned as is.
+func withField(l Logger, key string, value interface{}) Logger {
+	if fl, ok := l.(FieldLogger); ok {
+		return fl.With(key, value)
+	}
+	return l
+}
+
+// writerLogger is a Logger writing Info and Error messages to an io.Writer line by line.
+// Debug messages are dropped.
+type writerLogger struct {
+	w io.Writer
+}
+
+// NewStderrLogger is to create the default Logger, which writes Info and Error messages to stderr, so they
+// don't mix with the output of a program writing query results to stdout.
+func NewStderrLogger() Logger {
+	return &writerLogger{w: os.Stderr}
+}
+
+// NewStdoutLogger is to create a Logger writing Info and Error messages to stdout, where earlier versions of
+// the driver printed the query cost.
+func NewStdoutLogger() Logger {
+	return &writerLogger{w: os.Stdout}
+}
+
+// Debugf is to drop debug messages.
+func (l *writerLogger) Debugf(format string, args ...interface{}) {}
+
+// Infof is to write info messages.
+func (l *writerLogger) Infof(format string, args ...interface{}) {
+	fmt.Fprintf(l.w, format+"\n", args...)
+}
+
+// Errorf is to write error messages.
+func (l *writerLogger) Errorf(format string, args ...interface{}) {
+	fmt.Fprintf(l.w, format+"\n", args...)
+}
+
+// noOpsLogger is a Logger dropping all messages.
+type noOpsLogger struct{}
+
+// NewNoOpsLogger is to create a Logger to silence the driver output entirely.
+func NewNoOpsLogger() Logger {
+	return noOpsLogger{}
+}
+
+// Debugf is to drop debug messages.
+func (noOpsLogger) Debugf(format string, args ...interface{}) {}
+
+// Infof is to drop info messages.
+func (noOpsLogger) Infof(format string, args ...interface{}) {}
+
+// Errorf is to drop error messages.
+func (noOpsLogger) Errorf(format string, args ...interface{}) {}
+
+// zapLogger is a FieldLogger backed by zap.SugaredLogger.
+type zapLogger struct {
+	s *zap.SugaredLogger
+}
+
+// NewZapLogger is to create a Logger backed by a zap.Logger.
+func NewZapLogger(logger *zap.Logger) Logger {
+	return zapLogger{s: logger.Sugar()}
+}
+
+// Debugf is to log debug messages.
+func (l zapLogger) Debugf(format string, args ...interface{}) {
+	l.s.Debugf(format, args...)
+}
+
+// Infof is to log info messages.
+func (l zapLogger) Infof(format string, args ...interface{}) {
+	l.s.Infof(format, args...)
+}
+
+// Errorf is to log error messages.
+func (l zapLogger) Errorf(format string, args ...interface{}) {
+	l.s.Errorf(format, args...)
+}
+
+// With is to attach a structured field to all following messages.
+func (l zapLogger) With(key string, value interface{}) Logger {
+	return zapLogger{s: l.s.With(key, value)}
+}
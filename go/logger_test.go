@@ -0,0 +1,149 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package athenadriver
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// testLogger is a Logger recording all messages for testing purpose.
+type testLogger struct {
+	debugs []string
+	infos  []string
+	errors []string
+}
+
+func (l *testLogger) Debugf(format string, args ...interface{}) {
+	l.debugs = append(l.debugs, fmt.Sprintf(format, args...))
+}
+
+func (l *testLogger) Infof(format string, args ...interface{}) {
+	l.infos = append(l.infos, fmt.Sprintf(format, args...))
+}
+
+func (l *testLogger) Errorf(format string, args ...interface{}) {
+	l.errors = append(l.errors, fmt.Sprintf(format, args...))
+}
+
+func TestWriterLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := &writerLogger{w: &buf}
+	l.Debugf("debug %d", 1)
+	l.Infof("info %d", 2)
+	l.Errorf("error %d", 3)
+	assert.Equal(t, "info 2\nerror 3\n", buf.String())
+	assert.Equal(t, l, withField(l, "queryID", "abc"))
+}
+
+func TestNoOpsLogger(t *testing.T) {
+	l := NewNoOpsLogger()
+	l.Debugf("debug")
+	l.Infof("info")
+	l.Errorf("error")
+	assert.Equal(t, l, withField(l, "queryID", "abc"))
+}
+
+func TestZapLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := withField(NewZapLogger(zap.New(core)), "queryID", "abc")
+	l.Debugf("debug %d", 1)
+	l.Infof("info %d", 2)
+	l.Errorf("error %d", 3)
+	entries := logs.All()
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, "debug 1", entries[0].Message)
+	assert.Equal(t, zapcore.ErrorLevel, entries[2].Level)
+	for _, e := range entries {
+		assert.Equal(t, "abc", e.ContextMap()["queryID"])
+	}
+}
+
+func TestConfig_Logger(t *testing.T) {
+	testConf := NewNoOpsConfig()
+	assert.Equal(t, NewStderrLogger(), testConf.GetLogger())
+	assert.Equal(t, &writerLogger{w: os.Stdout}, NewStdoutLogger())
+	l := NewNoOpsLogger()
+	testConf.SetLogger(l)
+	assert.Equal(t, l, testConf.GetLogger())
+}
+
+func TestConnection_QueryContextLogger(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+	core, logs := observer.New(zapcore.DebugLevel)
+	c.connector.config.SetLogger(NewZapLogger(zap.New(core)))
+	c.connector.config.SetMoneyWise(true)
+
+	_, err := c.QueryContext(context.Background(), "SELECTExecContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	entries := logs.All()
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, "query started in workgroup henry_wu", entries[0].Message)
+	assert.Equal(t, "query succeeded", entries[1].Message)
+	assert.Equal(t, "query cost: 0.0000476837158203125 USD", entries[2].Message)
+	for _, e := range entries {
+		assert.Equal(t, "SELECTExecContext_OK_QID", e.ContextMap()["queryID"])
+	}
+}
+
+func TestConnection_ExecContextNoPrematureClose(t *testing.T) {
+	c := createConnectionFixture()
+	c.connector.config.SetLogging(true)
+	core, logs := observer.New(zapcore.DebugLevel)
+	c.connector.tracer.SetLogger(zap.New(core))
+
+	_, err := c.ExecContext(context.Background(), "SELECT_MULTIPLE_PAGES", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, logs.FilterMessageSnippet("rows close prematurely").Len())
+
+	rows, err := c.QueryContext(context.Background(), "SELECT_MULTIPLE_PAGES", []driver.NamedValue{})
+	assert.Nil(t, err)
+	assert.Nil(t, rows.Close())
+	assert.Equal(t, 1, logs.FilterMessageSnippet("rows close prematurely").Len())
+}
+
+func TestConnection_ExecContextCleanupError(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+	c.s3API = &mockS3Client{deleteErr: ErrTestMockGeneric}
+	c.connector.config.SetCleanupResults(true)
+	core, logs := observer.New(zapcore.DebugLevel)
+	c.connector.config.SetLogger(NewZapLogger(zap.New(core)))
+
+	// the query succeeded, so a failed cleanup is logged instead of returned
+	_, err := c.ExecContext(context.Background(), "SELECTQueryContext_OK", []driver.NamedValue{})
+	assert.Nil(t, err)
+	entries := logs.FilterMessageSnippet("failed to clean up query results").All()
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, zapcore.ErrorLevel, entries[0].Level)
+	assert.Contains(t, entries[0].Message, ErrTestMockGeneric.Error())
+	assert.Equal(t, "SELECTQueryContext_OK_QID", entries[0].ContextMap()["queryID"])
+}
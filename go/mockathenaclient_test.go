@@ -22,10 +22,14 @@ package athenadriver
 
 import (
 	"context"
+	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/athena"
 	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+	"strings"
+	"time"
 )
 
 // genQueryResultsOutputByToken is a function type with string as parameter.
@@ -42,6 +46,24 @@ type mockAthenaClient struct {
 	CreateWGStatus bool
 	GetWGStatus    bool
 	WGDisabled     bool
+	// wgConfig is the configuration of the workgroup got, GetDefaultWGConfig() if nil.
+	wgConfig *athena.WorkGroupConfiguration
+	// getWGCount is the number of GetWorkGroup calls.
+	getWGCount int
+	// lastCreateWorkGroupInput is the input of the last CreateWorkGroup call.
+	lastCreateWorkGroupInput *athena.CreateWorkGroupInput
+
+	// lastStartQueryExecutionInput is the input of the last StartQueryExecution call.
+	lastStartQueryExecutionInput *athena.StartQueryExecutionInput
+	// startQueryExecutionCount is the number of StartQueryExecution calls.
+	startQueryExecutionCount int
+
+	// statusPolls is the number of GetQueryExecution calls of QUEUED_RUNNING_SUCCEEDED_QID.
+	statusPolls int
+	// outputAccessDenied is the number of times SELECT_OUTPUT_ACCESS_DENIED fails before it succeeds.
+	outputAccessDenied int
+	// clientRequestTokens are the ClientRequestTokens of SELECT_OUTPUT_ACCESS_DENIED.
+	clientRequestTokens []string
 }
 
 func newMockAthenaClient() *mockAthenaClient {
@@ -61,6 +83,13 @@ func newMockAthenaClient() *mockAthenaClient {
 			"PING_OK_QID":                 PingResponse,
 			"SELECTExecContext_OK_QID":    PingResponse,
 			"SELECTQueryContext_OK_QID":   PingResponse,
+			"INSERT_3_ROWS_QID":           InsertResponse,
+			"CTAS_5_ROWS_QID":             CTASResponse,
+			"MERGE_7_ROWS_QID":            MergeResponse,
+			"NULL_MIXED_QID":              NullMixedResponse,
+			"PARSE_ERROR_QID":             ParseErrorResponse,
+			"JSON_QID":                    JSONResponse,
+			"DDL_QID":                     DDLResponse,
 		},
 	}
 	return &m
@@ -87,21 +116,32 @@ func (m *mockAthenaClient) GetQueryResultsWithContext(ctx aws.Context,
 	if *query.QueryExecutionId == "GetQueryResultsWithContext_return_error" {
 		return nil, ErrTestMockGeneric
 	}
+	if *query.QueryExecutionId == "EXPIRED_RESULTS_QID" {
+		return nil, awserr.New(athena.ErrCodeInvalidRequestException, "Query has expired", nil)
+	}
 	if nextToken == "GetQueryResultsWithContext_return_error" {
 		return nil, ErrTestMockGeneric
 	}
+	if nextToken == "GetQueryResultsWithContext_return_expired" {
+		return nil, awserr.New(athena.ErrCodeInvalidRequestException, "Query has expired", nil)
+	}
 	return m.queryToResultsGenMap[*query.QueryExecutionId](nextToken)
 }
 
 func (m *mockAthenaClient) GetWorkGroupWithContext(ctx aws.Context, gwi *athena.GetWorkGroupInput,
 	opt ...request.Option) (*athena.GetWorkGroupOutput, error) {
+	m.getWGCount++
 	if m.GetWGStatus {
 		enabled := "ENABLED"
 		if m.WGDisabled {
 			enabled = "DISABLED"
 		}
 		w := athena.WorkGroup{
-			State: &enabled,
+			State:         &enabled,
+			Configuration: GetDefaultWGConfig(),
+		}
+		if m.wgConfig != nil {
+			w.Configuration = m.wgConfig
 		}
 		a := athena.GetWorkGroupOutput{
 			WorkGroup: &w,
@@ -111,8 +151,9 @@ func (m *mockAthenaClient) GetWorkGroupWithContext(ctx aws.Context, gwi *athena.
 	return nil, ErrTestMockGeneric
 }
 
-func (m *mockAthenaClient) CreateWorkGroup(*athena.CreateWorkGroupInput) (
+func (m *mockAthenaClient) CreateWorkGroup(cwi *athena.CreateWorkGroupInput) (
 	*athena.CreateWorkGroupOutput, error) {
+	m.lastCreateWorkGroupInput = cwi
 	if !m.CreateWGStatus {
 		return nil, ErrTestMockGeneric
 	}
@@ -122,12 +163,93 @@ func (m *mockAthenaClient) CreateWorkGroup(*athena.CreateWorkGroupInput) (
 
 func (m *mockAthenaClient) StartQueryExecution(s *athena.
 	StartQueryExecutionInput) (*athena.StartQueryExecutionOutput, error) {
+	m.lastStartQueryExecutionInput = s
+	m.startQueryExecutionCount++
 	if *s.QueryString == "SELECT 1" { // Ping
 		qid := "PING_OK_QID"
 		return &athena.StartQueryExecutionOutput{
 			QueryExecutionId: &qid,
 		}, nil
 	}
+	if *s.QueryString == "SELECT_OUTPUT_ACCESS_DENIED" {
+		m.clientRequestTokens = append(m.clientRequestTokens, aws.StringValue(s.ClientRequestToken))
+		qid := "PING_OK_QID"
+		if m.outputAccessDenied > 0 {
+			m.outputAccessDenied--
+			qid = fmt.Sprintf("OUTPUT_ACCESS_DENIED_QID_%d", len(m.clientRequestTokens))
+		}
+		return &athena.StartQueryExecutionOutput{
+			QueryExecutionId: &qid,
+		}, nil
+	}
+	if *s.QueryString == "EXPLAIN SELECT * FROM t" {
+		qid := "PING_OK_QID"
+		return &athena.StartQueryExecutionOutput{
+			QueryExecutionId: &qid,
+		}, nil
+	}
+	if *s.QueryString == "EXPLAIN SELECT * FORM t" {
+		qid := "SYNTAX_ERROR_QID"
+		return &athena.StartQueryExecutionOutput{
+			QueryExecutionId: &qid,
+		}, nil
+	}
+	if *s.QueryString == "INSERT INTO t VALUES (1), (2), (3)" {
+		qid := "INSERT_3_ROWS_QID"
+		return &athena.StartQueryExecutionOutput{
+			QueryExecutionId: &qid,
+		}, nil
+	}
+	if *s.QueryString == "INSERT INTO \"avro_db\".events SELECT * FROM t" ||
+		*s.QueryString == "SELECT_UNSUPPORTED_FORMAT" {
+		qid := "UNSUPPORTED_FORMAT_QID"
+		return &athena.StartQueryExecutionOutput{
+			QueryExecutionId: &qid,
+		}, nil
+	}
+	if *s.QueryString == "CREATE TABLE t2 AS SELECT * FROM t1" ||
+		*s.QueryString == "CREATE TABLE t2 WITH (format = 'PARQUET') AS SELECT * FROM t1" {
+		qid := "CTAS_5_ROWS_QID"
+		return &athena.StartQueryExecutionOutput{
+			QueryExecutionId: &qid,
+		}, nil
+	}
+	if *s.QueryString == "MERGE INTO t USING s ON t.id = s.id WHEN MATCHED THEN DELETE" {
+		qid := "MERGE_7_ROWS_QID"
+		return &athena.StartQueryExecutionOutput{
+			QueryExecutionId: &qid,
+		}, nil
+	}
+	if *s.QueryString == "SELECT_NULL_MIXED" {
+		qid := "NULL_MIXED_QID"
+		return &athena.StartQueryExecutionOutput{
+			QueryExecutionId: &qid,
+		}, nil
+	}
+	if *s.QueryString == "SELECT_MULTIPLE_PAGES" {
+		qid := "SELECT_OK"
+		return &athena.StartQueryExecutionOutput{
+			QueryExecutionId: &qid,
+		}, nil
+	}
+	if *s.QueryString == "SELECT_JSON" {
+		qid := "JSON_QID"
+		return &athena.StartQueryExecutionOutput{
+			QueryExecutionId: &qid,
+		}, nil
+	}
+	if *s.QueryString == "SELECT_PARSE_ERROR" {
+		qid := "PARSE_ERROR_QID"
+		return &athena.StartQueryExecutionOutput{
+			QueryExecutionId: &qid,
+		}, nil
+	}
+	if *s.QueryString == "CREATE EXTERNAL TABLE t (a int)" {
+		qid := "DDL_QID"
+		return &athena.StartQueryExecutionOutput{
+			QueryExecutionId: &qid,
+		}, nil
+	}
 	if *s.QueryString == "SELECTExecContext_OK" { // Ping
 		qid := "SELECTExecContext_OK_QID"
 		return &athena.StartQueryExecutionOutput{
@@ -171,6 +293,12 @@ func (m *mockAthenaClient) StartQueryExecution(s *athena.
 			QueryExecutionId: &qid,
 		}, nil
 	}
+	if *s.QueryString == "SELECTQueryContext_BYTES_SCANNED_CUTOFF" {
+		qid := "SELECTQueryContext_BYTES_SCANNED_CUTOFF_QID"
+		return &athena.StartQueryExecutionOutput{
+			QueryExecutionId: &qid,
+		}, nil
+	}
 	if *s.QueryString == "StartQueryExecution_nil_error" {
 		return nil, ErrTestMockGeneric
 	}
@@ -206,6 +334,107 @@ func (m *mockAthenaClient) GetQueryExecutionWithContext(c aws.Context,
 	if *input.QueryExecutionId == "QueryExecutionStateFailed_QID" {
 		return nil, ErrTestMockFailedByAthena
 	}
+	if *input.QueryExecutionId == "INSERT_3_ROWS_QID" || *input.QueryExecutionId == "CTAS_5_ROWS_QID" ||
+		*input.QueryExecutionId == "MERGE_7_ROWS_QID" || *input.QueryExecutionId == "DDL_QID" ||
+		*input.QueryExecutionId == "NULL_MIXED_QID" || *input.QueryExecutionId == "PARSE_ERROR_QID" ||
+		*input.QueryExecutionId == "JSON_QID" || *input.QueryExecutionId == "EXPIRED_RESULTS_QID" ||
+		*input.QueryExecutionId == "SELECT_OK" {
+		stat := athena.QueryExecutionStateSucceeded
+		stt := athena.StatementTypeDml
+		if *input.QueryExecutionId == "CTAS_5_ROWS_QID" || *input.QueryExecutionId == "DDL_QID" {
+			stt = athena.StatementTypeDdl
+		}
+		var resultConfiguration *athena.ResultConfiguration
+		var statistics *athena.QueryExecutionStatistics
+		var submitted, completed *time.Time
+		if *input.QueryExecutionId == "INSERT_3_ROWS_QID" {
+			submitted = aws.Time(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+			completed = aws.Time(time.Date(2020, 1, 2, 3, 4, 12, 0, time.UTC))
+		}
+		if *input.QueryExecutionId == "INSERT_3_ROWS_QID" || *input.QueryExecutionId == "CTAS_5_ROWS_QID" {
+			prefix := "s3://query-results-henry-wu-us-east-2/" + *input.QueryExecutionId
+			resultConfiguration = &athena.ResultConfiguration{OutputLocation: aws.String(prefix + ".csv")}
+			statistics = &athena.QueryExecutionStatistics{DataManifestLocation: aws.String(prefix + "-manifest.csv")}
+		}
+		return &athena.GetQueryExecutionOutput{
+			QueryExecution: &athena.QueryExecution{
+				QueryExecutionId: input.QueryExecutionId,
+				Status: &athena.QueryExecutionStatus{
+					State:              &stat,
+					SubmissionDateTime: submitted,
+					CompletionDateTime: completed,
+				},
+				StatementType:       &stt,
+				ResultConfiguration: resultConfiguration,
+				Statistics:          statistics,
+			},
+		}, nil
+	}
+	if strings.HasPrefix(*input.QueryExecutionId, "OUTPUT_ACCESS_DENIED_QID") {
+		stat := athena.QueryExecutionStateFailed
+		reason := "Access denied when writing output to url: s3://query-results-henry-wu-us-east-2/" +
+			*input.QueryExecutionId + ".csv . Please ensure you are allowed to access the S3 bucket."
+		return &athena.GetQueryExecutionOutput{
+			QueryExecution: &athena.QueryExecution{
+				QueryExecutionId: input.QueryExecutionId,
+				Status: &athena.QueryExecutionStatus{
+					State:             &stat,
+					StateChangeReason: &reason,
+				},
+			},
+		}, nil
+	}
+	if *input.QueryExecutionId == "SYNTAX_ERROR_QID" {
+		stat := athena.QueryExecutionStateFailed
+		reason := "line 1:18: mismatched input 'FORM'. Expecting: <EOF>"
+		return &athena.GetQueryExecutionOutput{
+			QueryExecution: &athena.QueryExecution{
+				QueryExecutionId: input.QueryExecutionId,
+				Status: &athena.QueryExecutionStatus{
+					State:             &stat,
+					StateChangeReason: &reason,
+					AthenaError: &athena.AthenaError{
+						ErrorCategory: aws.Int64(ErrorCategoryUser),
+						ErrorType:     aws.Int64(1006),
+					},
+				},
+			},
+		}, nil
+	}
+	if *input.QueryExecutionId == "UNSUPPORTED_FORMAT_QID" {
+		stat := athena.QueryExecutionStateFailed
+		reason := "HIVE_UNSUPPORTED_FORMAT: Output format org.apache.hadoop.hive.ql.io.avro.AvroContainerOutputFormat " +
+			"with SerDe org.apache.hadoop.hive.serde2.avro.AvroSerDe is not supported."
+		return &athena.GetQueryExecutionOutput{
+			QueryExecution: &athena.QueryExecution{
+				QueryExecutionId: input.QueryExecutionId,
+				Status: &athena.QueryExecutionStatus{
+					State:             &stat,
+					StateChangeReason: &reason,
+				},
+			},
+		}, nil
+	}
+	if *input.QueryExecutionId == "QUEUED_RUNNING_SUCCEEDED_QID" {
+		states := []string{athena.QueryExecutionStateQueued, athena.QueryExecutionStateRunning,
+			athena.QueryExecutionStateSucceeded}
+		stat := states[m.statusPolls]
+		dataScanned := int64(m.statusPolls * 100)
+		submitted := time.Now().Add(-time.Minute)
+		m.statusPolls++
+		return &athena.GetQueryExecutionOutput{
+			QueryExecution: &athena.QueryExecution{
+				QueryExecutionId: input.QueryExecutionId,
+				Status: &athena.QueryExecutionStatus{
+					State:              &stat,
+					SubmissionDateTime: &submitted,
+				},
+				Statistics: &athena.QueryExecutionStatistics{
+					DataScannedInBytes: &dataScanned,
+				},
+			},
+		}, nil
+	}
 	if *input.QueryExecutionId == "PING_OK_QID" {
 		ping := "PING_OK_QID"
 		stat := athena.QueryExecutionStateSucceeded
@@ -240,6 +469,7 @@ func (m *mockAthenaClient) GetQueryExecutionWithContext(c aws.Context,
 		ping := "SELECTQueryContext_OK_QID"
 		stat := athena.QueryExecutionStateSucceeded
 		stt := "DDL"
+		outputLocation := "s3://query-results-henry-wu-us-east-2/SELECTQueryContext_OK_QID.csv"
 		return &athena.GetQueryExecutionOutput{
 			QueryExecution: &athena.QueryExecution{
 				Query:            &ping,
@@ -248,6 +478,9 @@ func (m *mockAthenaClient) GetQueryExecutionWithContext(c aws.Context,
 					State: &stat,
 				},
 				StatementType: &stt,
+				ResultConfiguration: &athena.ResultConfiguration{
+					OutputLocation: &outputLocation,
+				},
 			},
 		}, nil
 	}
@@ -302,6 +535,25 @@ func (m *mockAthenaClient) GetQueryExecutionWithContext(c aws.Context,
 			},
 		}, nil
 	}
+	if *input.QueryExecutionId == "SELECTQueryContext_BYTES_SCANNED_CUTOFF_QID" {
+		ping := "SELECTQueryContext_BYTES_SCANNED_CUTOFF_QID"
+		stat := athena.QueryExecutionStateCancelled
+		reason := "Query cancelled! : Bytes scanned limit was exceeded"
+		var dataScanned = int64(DefaultBytesScannedCutoffPerQuery + 1)
+		return &athena.GetQueryExecutionOutput{
+			QueryExecution: &athena.QueryExecution{
+				Query:            &ping,
+				QueryExecutionId: &ping,
+				Status: &athena.QueryExecutionStatus{
+					State:             &stat,
+					StateChangeReason: &reason,
+				},
+				Statistics: &athena.QueryExecutionStatistics{
+					DataScannedInBytes: &dataScanned,
+				},
+			},
+		}, nil
+	}
 	if *input.QueryExecutionId == "SELECTQueryContext_CANCEL_FAIL_QID" {
 		ping := "SELECTQueryContext_CANCEL_FAIL_QID"
 		stat := athena.QueryExecutionStateQueued
@@ -573,6 +825,134 @@ func headPageWithColumnButNoRowResponse(token string) (*athena.GetQueryResultsOu
 	}
 }
 
+// updateCountResponse is the result of INSERT INTO or CTAS, which has a `rows` column but no row.
+func updateCountResponse(token string, updateCount int64) (*athena.GetQueryResultsOutput, error) {
+	switch token {
+	case "":
+		return &athena.GetQueryResultsOutput{
+			ResultSet: &athena.ResultSet{
+				ResultSetMetadata: &athena.ResultSetMetadata{
+					ColumnInfo: []*athena.ColumnInfo{
+						newColumnInfo("rows", "bigint"),
+					},
+				},
+			},
+			UpdateCount: &updateCount,
+		}, nil
+	default:
+		return nil, ErrTestMockGeneric
+	}
+}
+
+func InsertResponse(token string) (*athena.GetQueryResultsOutput, error) {
+	return updateCountResponse(token, 3)
+}
+
+func CTASResponse(token string) (*athena.GetQueryResultsOutput, error) {
+	return updateCountResponse(token, 5)
+}
+
+func MergeResponse(token string) (*athena.GetQueryResultsOutput, error) {
+	return updateCountResponse(token, 7)
+}
+
+// newDataRow is to create a row of data, where nil is NULL.
+func newDataRow(values ...*string) *athena.Row {
+	row := &athena.Row{}
+	for _, v := range values {
+		row.Data = append(row.Data, &athena.Datum{VarCharValue: v})
+	}
+	return row
+}
+
+// NullMixedResponse has NULL and present values in the same columns across rows.
+func NullMixedResponse(token string) (*athena.GetQueryResultsOutput, error) {
+	switch token {
+	case "":
+		return &athena.GetQueryResultsOutput{
+			ResultSet: &athena.ResultSet{
+				ResultSetMetadata: &athena.ResultSetMetadata{
+					ColumnInfo: []*athena.ColumnInfo{
+						newColumnInfo("i", "bigint"),
+						newColumnInfo("f", "double"),
+						newColumnInfo("b", "boolean"),
+						newColumnInfo("t", "timestamp"),
+						newColumnInfo("s", "varchar"),
+					},
+				},
+				Rows: []*athena.Row{
+					newDataRow(aws.String("42"), aws.String("3.5"), aws.String("true"),
+						aws.String("2020-01-20 15:28:35.123"), aws.String("a")),
+					newDataRow(nil, nil, nil, nil, nil),
+					newDataRow(aws.String("-1"), aws.String("0"), aws.String("false"),
+						aws.String("1970-01-01 00:00:00.000"), aws.String("")),
+				},
+			},
+		}, nil
+	default:
+		return nil, ErrTestMockGeneric
+	}
+}
+
+// ParseErrorResponse has a bigint column with a value that isn't an integer.
+func ParseErrorResponse(token string) (*athena.GetQueryResultsOutput, error) {
+	switch token {
+	case "":
+		return &athena.GetQueryResultsOutput{
+			ResultSet: &athena.ResultSet{
+				ResultSetMetadata: &athena.ResultSetMetadata{
+					ColumnInfo: []*athena.ColumnInfo{
+						newColumnInfo("amount", "bigint"),
+					},
+				},
+				Rows: []*athena.Row{
+					newDataRow(aws.String("12")),
+					newDataRow(aws.String("twelve")),
+				},
+			},
+		}, nil
+	default:
+		return nil, ErrTestMockGeneric
+	}
+}
+
+func JSONResponse(token string) (*athena.GetQueryResultsOutput, error) {
+	switch token {
+	case "":
+		return &athena.GetQueryResultsOutput{
+			ResultSet: &athena.ResultSet{
+				ResultSetMetadata: &athena.ResultSetMetadata{
+					ColumnInfo: []*athena.ColumnInfo{
+						newColumnInfo("id", "bigint"),
+						newColumnInfo("doc", "json"),
+					},
+				},
+				Rows: []*athena.Row{
+					newDataRow(aws.String("1"), aws.String(`{"a":[1,"x"]}`)),
+					newDataRow(aws.String("2"), nil),
+				},
+			},
+		}, nil
+	default:
+		return nil, ErrTestMockGeneric
+	}
+}
+
+func DDLResponse(token string) (*athena.GetQueryResultsOutput, error) {
+	switch token {
+	case "":
+		var i int64
+		return &athena.GetQueryResultsOutput{
+			ResultSet: &athena.ResultSet{
+				ResultSetMetadata: &athena.ResultSetMetadata{},
+			},
+			UpdateCount: &i,
+		}, nil
+	default:
+		return nil, ErrTestMockGeneric
+	}
+}
+
 func PingResponse(token string) (*athena.GetQueryResultsOutput,
 	error) {
 	switch token {
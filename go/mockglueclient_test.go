@@ -0,0 +1,51 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package athenadriver
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/glue"
+	"github.com/aws/aws-sdk-go/service/glue/glueiface"
+)
+
+type mockGlueClient struct {
+	glueiface.GlueAPI
+	// tables is a map from `db.table` to the table.
+	tables map[string]*glue.TableData
+	// getTableCalls is the list of `db.table` got.
+	getTableCalls []string
+}
+
+func (m *mockGlueClient) GetTableWithContext(ctx aws.Context, input *glue.GetTableInput,
+	opts ...request.Option) (*glue.GetTableOutput, error) {
+	key := *input.DatabaseName + "." + *input.Name
+	m.getTableCalls = append(m.getTableCalls, key)
+	table, ok := m.tables[key]
+	if !ok {
+		return nil, ErrTestMockGeneric
+	}
+	return &glue.GetTableOutput{Table: table}, nil
+}
+
+func newGlueColumn(name string, comment string) *glue.Column {
+	return &glue.Column{Name: aws.String(name), Comment: aws.String(comment)}
+}
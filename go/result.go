@@ -20,10 +20,34 @@
 
 package athenadriver
 
+import "time"
+
 // AthenaResult is the result of an Athena query execution.
 type AthenaResult struct {
 	lastInsertedID int64
 	rowAffected    int64
+	dataScanned    int64
+	costUSD        float64
+	update         UpdateResult
+	submitted      *time.Time
+	completed      *time.Time
+}
+
+// UpdateResult is what a statement writing data changed in Athena, and where its output landed in S3.
+type UpdateResult struct {
+	QueryID string
+	// Has is false if the statement doesn't write data, like SELECT, or Athena reported no UpdateCount.
+	// UpdateCount and UpdateType are zero values then.
+	Has         bool
+	UpdateCount int64
+	// UpdateType is one of INSERT, UPDATE, DELETE, MERGE and CREATE TABLE AS SELECT. The version of Athena Go
+	// SDK used doesn't have UpdateType in GetQueryResultsOutput yet, so it is got from the statement.
+	UpdateType string
+	// OutputLocation is the S3 URI of the result file of the query, empty if Athena didn't report it.
+	OutputLocation string
+	// ManifestLocation is the S3 URI of the manifest file listing the files written by the query, like
+	// for INSERT INTO and CTAS, empty if there is none.
+	ManifestLocation string
 }
 
 // LastInsertId returns the database's auto-generated ID
@@ -33,7 +57,36 @@ func (a AthenaResult) LastInsertId() (int64, error) {
 	return -1, nil
 }
 
-// RowsAffected returns the number of rows affected by the query.
+// RowsAffected returns the number of rows affected by the query. It is the UpdateCount Athena reports in
+// GetQueryResults, i.e. the number of rows written by INSERT INTO, CTAS and CVAS, or by MERGE, UPDATE and DELETE
+// for Iceberg tables, and 0 for DDL statements.
 func (a AthenaResult) RowsAffected() (int64, error) {
 	return a.rowAffected, nil
 }
+
+// DataScannedInBytes returns the bytes of data the query scanned in Athena.
+func (a AthenaResult) DataScannedInBytes() int64 {
+	return a.dataScanned
+}
+
+// CostUSD returns the estimated cost in USD of the query, at the PricePerTB of Config. As database/sql wraps
+// driver.Result, it is only available with sql.Conn.Raw(), like from the results of ExecScript.
+func (a AthenaResult) CostUSD() float64 {
+	return a.costUSD
+}
+
+// UpdateResult returns what the statement changed and where its output landed. As database/sql wraps
+// driver.Result, it is only available with sql.Conn.Raw(), like from the results of ExecScript.
+func (a AthenaResult) UpdateResult() UpdateResult {
+	return a.update
+}
+
+// SubmissionDateTime returns when the query was submitted to Athena. The bool is false if Athena didn't report it.
+func (a AthenaResult) SubmissionDateTime() (time.Time, bool) {
+	return timeValue(a.submitted)
+}
+
+// CompletionDateTime returns when the query completed in Athena. The bool is false if Athena didn't report it.
+func (a AthenaResult) CompletionDateTime() (time.Time, bool) {
+	return timeValue(a.completed)
+}
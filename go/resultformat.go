@@ -0,0 +1,184 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package athenadriver
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// resultFormat is how a result format is written in the WITH clause of CTAS and UNLOAD.
+type resultFormat struct {
+	format         string
+	fieldDelimiter string // the SQL literal of field_delimiter, empty if not applicable
+}
+
+// resultFormats are the result formats which can be set with ResultFormatKey.
+// https://docs.aws.amazon.com/athena/latest/ug/create-table-as.html#ctas-table-properties
+var resultFormats = map[string]resultFormat{
+	"CSV":     {format: "TEXTFILE", fieldDelimiter: ","},
+	"TSV":     {format: "TEXTFILE", fieldDelimiter: `\t`},
+	"PARQUET": {format: "PARQUET"},
+	"ORC":     {format: "ORC"},
+	"JSON":    {format: "JSON"},
+	"AVRO":    {format: "AVRO"},
+}
+
+var (
+	reFormatProperty         = regexp.MustCompile(`(?i)\bformat\s*=\s*'([^']*)'`)
+	reFieldDelimiterProperty = regexp.MustCompile(`(?i)\bfield_delimiter\s*=\s*'([^']*)'`)
+)
+
+// withResultFormat is to set the result format of a CTAS or UNLOAD statement in its WITH clause, like:
+//   CREATE TABLE t WITH (format = 'PARQUET') AS SELECT * FROM s
+//   UNLOAD (SELECT * FROM s) TO 's3://bucket/prefix/' WITH (format = 'TEXTFILE', field_delimiter = ',')
+// The WITH clause is added if it is missing. If the query already has a format, it must be the same.
+func withResultFormat(query string, format string) (string, error) {
+	f, ok := resultFormats[strings.ToUpper(strings.TrimSpace(format))]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrInvalidResultFormat, format)
+	}
+	words := topLevelWords(query)
+	// the WITH clause is before AS of CTAS, or after TO of UNLOAD
+	withIdx, asIdx := -1, -1
+	switch {
+	case len(words) > 2 && words[0].word == "create" && words[1].word == "table":
+		for i := 2; i < len(words) && asIdx == -1; i++ {
+			switch words[i].word {
+			case "with":
+				withIdx = i
+			case "as":
+				asIdx = i
+			}
+		}
+		if asIdx == -1 {
+			return "", ErrResultFormatNotApplicable
+		}
+	case len(words) > 0 && words[0].word == "unload":
+		afterTo := false
+		for i := 1; i < len(words); i++ {
+			if words[i].word == "to" {
+				afterTo = true
+			} else if afterTo && words[i].word == "with" {
+				withIdx = i
+				break
+			}
+		}
+	default:
+		return "", ErrResultFormatNotApplicable
+	}
+
+	properties := "format = '" + f.format + "'"
+	if f.fieldDelimiter != "" {
+		properties += ", field_delimiter = '" + f.fieldDelimiter + "'"
+	}
+	if withIdx == -1 {
+		if asIdx != -1 {
+			return query[:words[asIdx].start] + "WITH (" + properties + ") " + query[words[asIdx].start:], nil
+		}
+		return strings.TrimRight(query, " \t\r\n;") + " WITH (" + properties + ")", nil
+	}
+
+	open := words[withIdx].end
+	for open < len(query) && strings.IndexByte(" \t\r\n", query[open]) >= 0 {
+		open++
+	}
+	if open >= len(query) || query[open] != '(' {
+		return "", ErrInvalidQuery
+	}
+	closing, ok := matchingParen(query, open)
+	if !ok {
+		return "", ErrInvalidQuery
+	}
+	existing := query[open+1 : closing]
+	m := reFormatProperty.FindStringSubmatch(existing)
+	if m == nil {
+		if strings.TrimSpace(existing) != "" {
+			properties += ", "
+		}
+		return query[:open+1] + properties + query[open+1:], nil
+	}
+	if !strings.EqualFold(m[1], f.format) {
+		return "", fmt.Errorf("%w: %s is set, but %s is requested", ErrResultFormatConflict, m[0], format)
+	}
+	if f.fieldDelimiter == "" {
+		return query, nil
+	}
+	d := reFieldDelimiterProperty.FindStringSubmatch(existing)
+	if d == nil {
+		return query[:open+1] + "field_delimiter = '" + f.fieldDelimiter + "', " + query[open+1:], nil
+	}
+	if d[1] != f.fieldDelimiter {
+		return "", fmt.Errorf("%w: %s is set, but %s is requested", ErrResultFormatConflict, d[0], format)
+	}
+	return query, nil
+}
+
+// topLevelWord is a word of a query, which is not in string literals, quoted identifiers, comments
+// or parentheses.
+type topLevelWord struct {
+	word       string // in lower case
+	start, end int
+}
+
+// topLevelWords is to find the top level words of query. Words after an unterminated quote or block
+// comment are not found.
+func topLevelWords(query string) []topLevelWord {
+	tokens, _ := sqlTokens(query)
+	var words []topLevelWord
+	depth := 0
+	for _, t := range tokens {
+		switch {
+		case t.kind == sqlTokenSymbol && query[t.start] == '(':
+			depth++
+		case t.kind == sqlTokenSymbol && query[t.start] == ')':
+			depth--
+		case t.kind == sqlTokenWord && depth == 0:
+			words = append(words, topLevelWord{word: strings.ToLower(query[t.start:t.end]), start: t.start, end: t.end})
+		}
+	}
+	return words
+}
+
+// matchingParen is to find the index of the `)` matching the `(` at index open of query, skipping
+// those in string literals, quoted identifiers and comments.
+func matchingParen(query string, open int) (int, bool) {
+	depth := 0
+	for i := open; ; {
+		t, ok := nextSQLToken(query, i)
+		if !ok || t.kind == sqlTokenEOF {
+			return 0, false
+		}
+		i = t.end
+		if t.kind != sqlTokenSymbol {
+			continue
+		}
+		switch query[t.start] {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return t.start, true
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package athenadriver
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithResultFormat(t *testing.T) {
+	tests := []struct {
+		query    string
+		format   string
+		expected string
+	}{
+		{"CREATE TABLE t AS SELECT * FROM s", "parquet",
+			"CREATE TABLE t WITH (format = 'PARQUET') AS SELECT * FROM s"},
+		{"create table db.t as (select 'a as b' as c)", "CSV",
+			"create table db.t WITH (format = 'TEXTFILE', field_delimiter = ',') as (select 'a as b' as c)"},
+		{"CREATE TABLE t WITH (external_location = 's3://b/p/') AS SELECT 1", "ORC",
+			"CREATE TABLE t WITH (format = 'ORC', external_location = 's3://b/p/') AS SELECT 1"},
+		{"CREATE TABLE t WITH (format = 'orc') AS SELECT 1", "ORC",
+			"CREATE TABLE t WITH (format = 'orc') AS SELECT 1"},
+		{"CREATE TABLE t WITH (format = 'TEXTFILE') AS SELECT 1", "TSV",
+			`CREATE TABLE t WITH (field_delimiter = '\t', format = 'TEXTFILE') AS SELECT 1`},
+		{"CREATE TABLE t AS WITH x AS (SELECT 1) SELECT * FROM x", "JSON",
+			"CREATE TABLE t WITH (format = 'JSON') AS WITH x AS (SELECT 1) SELECT * FROM x"},
+		{"UNLOAD (SELECT * FROM s WHERE c = 'with') TO 's3://b/p/';", "avro",
+			"UNLOAD (SELECT * FROM s WHERE c = 'with') TO 's3://b/p/' WITH (format = 'AVRO')"},
+		{"UNLOAD (SELECT 1) TO 's3://b/p/' WITH (compression = 'gzip')", "TSV",
+			`UNLOAD (SELECT 1) TO 's3://b/p/' WITH (format = 'TEXTFILE', field_delimiter = '\t', compression = 'gzip')`},
+		{"UNLOAD (SELECT 1) TO 's3://b/p/' WITH ( )", "JSON",
+			"UNLOAD (SELECT 1) TO 's3://b/p/' WITH (format = 'JSON' )"},
+		{"CREATE TABLE t WITH (format = 'ORC' /* ) */) AS SELECT 1", "ORC",
+			"CREATE TABLE t WITH (format = 'ORC' /* ) */) AS SELECT 1"},
+		{"CREATE TABLE t WITH (external_location = 's3://b/p/' -- )\r) AS SELECT 1", "JSON",
+			"CREATE TABLE t WITH (format = 'JSON', external_location = 's3://b/p/' -- )\r) AS SELECT 1"},
+	}
+	for _, test := range tests {
+		q, err := withResultFormat(test.query, test.format)
+		assert.Nil(t, err, test.query)
+		assert.Equal(t, test.expected, q)
+	}
+
+	errorTests := []struct {
+		query    string
+		format   string
+		expected error
+	}{
+		{"CREATE TABLE t AS SELECT 1", "XML", ErrInvalidResultFormat},
+		{"SELECT * FROM t", "CSV", ErrResultFormatNotApplicable},
+		{"CREATE TABLE t (a int)", "CSV", ErrResultFormatNotApplicable},
+		{"CREATE VIEW v AS SELECT 1", "CSV", ErrResultFormatNotApplicable},
+		{"INSERT INTO t SELECT 1", "CSV", ErrResultFormatNotApplicable},
+		{"CREATE TABLE t WITH (format = 'ORC') AS SELECT 1", "PARQUET", ErrResultFormatConflict},
+		{"UNLOAD (SELECT 1) TO 's3://b/p/' WITH (format = 'TEXTFILE', field_delimiter = ',')", "TSV",
+			ErrResultFormatConflict},
+		{"CREATE TABLE t WITH format AS SELECT 1", "ORC", ErrInvalidQuery},
+		{"CREATE TABLE t WITH (format = 'ORC' AS SELECT 1", "ORC", ErrResultFormatNotApplicable},
+	}
+	for _, test := range errorTests {
+		_, err := withResultFormat(test.query, test.format)
+		assert.True(t, errors.Is(err, test.expected), test.query)
+	}
+}
+
+func TestTopLevelWords(t *testing.T) {
+	var words []string
+	for _, w := range topLevelWords("Select 'a b' AS \"c d\", f(x) -- e\n/* g */ FROM t2") {
+		words = append(words, w.word)
+	}
+	assert.Equal(t, []string{"select", "as", "f", "from", "t2"}, words)
+
+	words = nil
+	for _, w := range topLevelWords("SELECT 'it''s (' AS a -- b\rFROM /* ( */ t") {
+		words = append(words, w.word)
+	}
+	assert.Equal(t, []string{"select", "as", "a", "from", "t"}, words)
+}
+
+func TestMatchingParen(t *testing.T) {
+	tests := map[string]int{
+		"(a)":                2,
+		"(a, (b), ')')":      12,
+		"(a /* ) */)":        10,
+		"(a -- )\n)":         8,
+		"(a -- )\r)":         8,
+		"(\"a)\" ` ) ` '' )": 15,
+	}
+	for query, expected := range tests {
+		closing, ok := matchingParen(query, 0)
+		assert.True(t, ok, query)
+		assert.Equal(t, expected, closing, query)
+	}
+	for _, query := range []string{"(a", "(a /* )", "(a ')", "(a -- )"} {
+		_, ok := matchingParen(query, 0)
+		assert.False(t, ok, query)
+	}
+}
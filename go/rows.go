@@ -32,6 +32,9 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+	"github.com/aws/aws-sdk-go/service/glue/glueiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/athena"
@@ -47,6 +50,15 @@ type Rows struct {
 	config          *Config
 	tracer          *DriverTracer
 	pageCount       int64
+	outputLocation  *string
+	dataScanned     int64
+	manifest        string
+	updateType      string
+	submitted       *time.Time
+	completed       *time.Time
+	singlePage      bool
+	s3API           s3iface.S3API // set only if the result files should be deleted on Close
+	glueAPI         glueiface.GlueAPI
 }
 
 // NewRows is to create a new Rows.
@@ -66,6 +78,58 @@ func NewRows(ctx context.Context, athenaAPI athenaiface.AthenaAPI, queryID strin
 	return &r, nil
 }
 
+// QueryID returns the QueryExecutionId of the query.
+func (r *Rows) QueryID() string {
+	return r.queryID
+}
+
+// OutputLocation returns the S3 URI where Athena wrote the result of the query, ie
+// the configured output bucket with the query ID appended, like:
+//   s3://query-results-bucket/prefix/1e2e1ec4-c81c-4e4d-9f5b-8a1b52bd6b0f.csv
+// The bool is false if Athena didn't report a location, which is the case for some UTILITY statements.
+func (r *Rows) OutputLocation() (string, bool) {
+	if r.outputLocation == nil || *r.outputLocation == "" {
+		return "", false
+	}
+	return *r.outputLocation, true
+}
+
+// DataScannedInBytes returns the bytes of data the query scanned in Athena. It is 0 for the rows of a
+// query got by QueryResultsByID.
+func (r *Rows) DataScannedInBytes() int64 {
+	return r.dataScanned
+}
+
+// CostUSD returns the estimated cost in USD of the query, at the PricePerTB of Config.
+func (r *Rows) CostUSD() float64 {
+	return costUSD(r.dataScanned, r.config.GetPricePerTB())
+}
+
+// SubmissionDateTime returns when the query was submitted to Athena, which is before it was queued, so
+// together with CompletionDateTime it is the time the query took in Athena. The bool is false if Athena
+// didn't report it.
+func (r *Rows) SubmissionDateTime() (time.Time, bool) {
+	return timeValue(r.submitted)
+}
+
+// CompletionDateTime returns when the query completed in Athena. The bool is false if Athena didn't report it.
+func (r *Rows) CompletionDateTime() (time.Time, bool) {
+	return timeValue(r.completed)
+}
+
+// UpdateResult returns the UpdateCount Athena reported for the statement, its type, and the output and
+// manifest locations of the query. Has is false for a statement not writing data, like SELECT.
+func (r *Rows) UpdateResult() UpdateResult {
+	u := UpdateResult{QueryID: r.queryID, ManifestLocation: r.manifest}
+	u.OutputLocation, _ = r.OutputLocation()
+	if r.updateType != "" && r.ResultOutput != nil && r.ResultOutput.UpdateCount != nil {
+		u.Has = true
+		u.UpdateCount = *r.ResultOutput.UpdateCount
+		u.UpdateType = r.updateType
+	}
+	return u
+}
+
 // Columns return Columns metadata.
 func (r *Rows) Columns() []string {
 	var columns []string
@@ -97,6 +161,11 @@ func (r *Rows) Next(dest []driver.Value) error {
 			r.reachedLastPage = true
 			return io.EOF
 		}
+		if r.singlePage {
+			// Rows from FetchPage stops at the end of its page
+			r.reachedLastPage = true
+			return io.EOF
+		}
 
 		if err := r.fetchNextPage(r.ResultOutput.NextToken); err != nil {
 			return err
@@ -218,6 +287,47 @@ func (r *Rows) Close() error {
 		r.ResultOutput = nil
 	}
 	r.reachedLastPage = true
+	return r.cleanupResults()
+}
+
+// cleanupResults is to delete the result file and its metadata file of the query from S3.
+// It runs at most once, and only for Rows of a query started by the driver with CleanupResults enabled.
+func (r *Rows) cleanupResults() error {
+	if r.s3API == nil {
+		return nil
+	}
+	s3API := r.s3API
+	r.s3API = nil
+	location, ok := r.OutputLocation()
+	if !ok {
+		return nil
+	}
+	bucket, key, ok := parseS3URI(location)
+	if !ok {
+		return nil
+	}
+	resp, err := s3API.DeleteObjectsWithContext(context.Background(), &s3.DeleteObjectsInput{
+		Bucket: aws.String(bucket),
+		Delete: &s3.Delete{
+			Objects: []*s3.ObjectIdentifier{
+				{Key: aws.String(key)},
+				{Key: aws.String(key + ".metadata")},
+			},
+			Quiet: aws.Bool(true),
+		},
+	})
+	if err == nil && len(resp.Errors) > 0 {
+		err = fmt.Errorf("failed to delete %s: %s", aws.StringValue(resp.Errors[0].Key),
+			aws.StringValue(resp.Errors[0].Message))
+	}
+	if err != nil {
+		r.tracer.Log(ErrorLevel, "failed to delete query results",
+			zap.String("queryID", r.queryID),
+			zap.String("location", location),
+			zap.String("error", err.Error()))
+		r.tracer.Scope().Counter(DriverName + ".failure.rows.cleanupresults").Inc(1)
+		return err
+	}
 	return nil
 }
 
@@ -232,6 +342,10 @@ func (r *Rows) convertRow(columns []*athena.ColumnInfo, rdata []*athena.Datum, r
 		if err != nil {
 			r.tracer.Log(ErrorLevel, "convertrow failed", zap.String("error", err.Error()))
 			r.tracer.Scope().Counter(DriverName + ".failure.convertrow").Inc(1)
+			if val.VarCharValue != nil {
+				return fmt.Errorf("failed to convert value of column %s (%s): %w", *columns[i].Name,
+					*columns[i].Type, err)
+			}
 			return err
 		}
 		/*r.tracer.Log(DebugLevel, "TM",
@@ -266,6 +380,8 @@ func (r *Rows) athenaTypeToGoType(columnInfo *athena.ColumnInfo, rawValue *strin
 			return "", nil
 		} else if driverConfig.IsMissingAsDefault() {
 			return r.getDefaultValueForColumnType(*columnInfo.Type), nil
+		} else if driverConfig.IsMissingAsNil() {
+			return nil, nil
 		}
 		r.tracer.Scope().Counter(DriverName + ".failure.convertvalue.config").Inc(1)
 		r.tracer.Log(ErrorLevel, "missing data", zap.String("columnInfo.Name", *columnInfo.Name))
@@ -310,9 +426,18 @@ func (r *Rows) athenaTypeToGoType(columnInfo *athena.ColumnInfo, rawValue *strin
 			return nil, err
 		}
 		return f, nil
-	// for binary, we assume all chars are 0 or 1; for json,
-	// we assume the json syntax is correct. Leave to caller to verify it.
-	case "json", "char", "varchar", "varbinary", "row", "string", "binary",
+	// json is returned as raw bytes, so it can be scanned into *json.RawMessage or a sql.Scanner
+	// like ScanJSON() intact, unless JSONAsString is set. We assume the json syntax is correct.
+	// Leave to caller to verify it.
+	case "json":
+		if driverConfig.IsJSONAsString() {
+			return val, nil
+		}
+		return []byte(val), nil
+	// varbinary and binary are returned as the bytes of the hexadecimal text Athena returns, like `68 65 6c 6c 6f`.
+	case "varbinary", "binary":
+		return []byte(val), nil
+	case "char", "varchar", "row", "string",
 		"struct", "interval year to month", "interval day to second", "decimal",
 		"ipaddress", "array", "map", "unknown":
 		return val, nil
@@ -355,7 +480,14 @@ func (r *Rows) getDefaultValueForColumnType(athenaType string) interface{} {
 		return 0.0
 	case "date", "time", "time with time zone", "timestamp", "timestamp with time zone":
 		return time.Time{}
-	case "json", "char", "varchar", "varbinary", "row", "string", "binary",
+	case "json":
+		if r.config.IsJSONAsString() {
+			return ""
+		}
+		return []byte{}
+	case "varbinary", "binary":
+		return []byte{}
+	case "char", "varchar", "row", "string",
 		"struct", "interval year to month", "interval day to second", "decimal",
 		"ipaddress", "array", "map", "unknown":
 		return ""
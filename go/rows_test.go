@@ -22,9 +22,12 @@ package athenadriver
 
 import (
 	"context"
+	"database/sql"
 	"database/sql/driver"
+	"errors"
 	"io"
 	"reflect"
+	"strconv"
 	"testing"
 	"time"
 
@@ -252,11 +255,18 @@ func TestRows_GetDefaultValueForColumnType(t *testing.T) {
 		for _, v := range []string{"tinyint", "smallint", "integer", "bigint"} {
 			assert.Equal(t, r.getDefaultValueForColumnType(v), 0)
 		}
-		for _, v := range []string{"json", "char", "varchar", "varbinary", "row", "string", "binary",
+		for _, v := range []string{"char", "varchar", "row", "string",
 			"struct", "interval year to month", "interval day to second", "decimal",
 			"ipaddress", "array", "map", "unknown"} {
 			assert.Equal(t, r.getDefaultValueForColumnType(v), "")
 		}
+		for _, v := range []string{"varbinary", "binary"} {
+			assert.Equal(t, r.getDefaultValueForColumnType(v), []byte{})
+		}
+		assert.Equal(t, r.getDefaultValueForColumnType("json"), []byte{})
+		testConf.SetJSONAsString(true)
+		assert.Equal(t, r.getDefaultValueForColumnType("json"), "")
+		testConf.SetJSONAsString(false)
 		for _, v := range []string{"float", "double", "real"} {
 			assert.Equal(t, r.getDefaultValueForColumnType(v), 0.0)
 		}
@@ -356,9 +366,32 @@ func TestRows_AthenaTypeToGoType(t *testing.T) {
 	assert.NotNil(t, e)
 	assert.Nil(t, g)
 
+	// json
+	c = newColumnInfo("a", "json")
+	rv = `{"a":[1,2],"b":"x,\ny"}`
+	g, e = r.athenaTypeToGoType(c, &rv, testConf)
+	assert.Nil(t, e)
+	assert.Equal(t, []byte(rv), g)
+
+	// json as string, like before it was returned as []byte
+	testConf.SetJSONAsString(true)
+	g, e = r.athenaTypeToGoType(c, &rv, testConf)
+	assert.Nil(t, e)
+	assert.Equal(t, rv, g)
+	testConf.SetJSONAsString(false)
+
+	// varbinary
+	for _, s := range []string{"varbinary", "binary"} {
+		c = newColumnInfo("a", s)
+		rv = "68 65 6c 6c 6f"
+		g, e = r.athenaTypeToGoType(c, &rv, testConf)
+		assert.Nil(t, e)
+		assert.Equal(t, []byte(rv), g)
+	}
+
 	// string-like
-	for _, s := range []string{"json", "char", "varchar", "varbinary", "row",
-		"string", "binary",
+	for _, s := range []string{"char", "varchar", "row",
+		"string",
 		"struct", "interval year to month", "interval day to second", "decimal",
 		"ipaddress", "array", "map", "unknown"} {
 		c = newColumnInfo("a", s)
@@ -562,3 +595,66 @@ func TestRows_NewRows(t *testing.T) {
 	}
 
 }
+
+func TestRows_ScanNullTypes(t *testing.T) {
+	c := createConnectionFixture()
+	c.connector.config.SetMissingAsEmptyString(false)
+	c.connector.config.SetMissingAsDefault(false)
+	c.connector.config.SetMissingAsNil(true)
+	db := sql.OpenDB(fixtureConnector{conn: c})
+	defer db.Close()
+
+	rows, err := db.Query("SELECT_NULL_MIXED")
+	assert.Nil(t, err)
+	defer rows.Close()
+	var is []sql.NullInt64
+	var fs []sql.NullFloat64
+	var bs []sql.NullBool
+	var ts []sql.NullTime
+	var ss []sql.NullString
+	for rows.Next() {
+		var i sql.NullInt64
+		var f sql.NullFloat64
+		var b sql.NullBool
+		var ti sql.NullTime
+		var s sql.NullString
+		assert.Nil(t, rows.Scan(&i, &f, &b, &ti, &s))
+		is = append(is, i)
+		fs = append(fs, f)
+		bs = append(bs, b)
+		ts = append(ts, ti)
+		ss = append(ss, s)
+	}
+	assert.Nil(t, rows.Err())
+	assert.Equal(t, []sql.NullInt64{{Int64: 42, Valid: true}, {}, {Int64: -1, Valid: true}}, is)
+	assert.Equal(t, []sql.NullFloat64{{Float64: 3.5, Valid: true}, {}, {Float64: 0, Valid: true}}, fs)
+	assert.Equal(t, []sql.NullBool{{Bool: true, Valid: true}, {}, {Bool: false, Valid: true}}, bs)
+	assert.Len(t, ts, 3)
+	assert.True(t, ts[0].Valid)
+	assert.Equal(t, time.Date(2020, 1, 20, 15, 28, 35, 123000000, time.UTC), ts[0].Time.UTC())
+	assert.False(t, ts[1].Valid)
+	assert.True(t, ts[2].Valid)
+	assert.Equal(t, []sql.NullString{{String: "a", Valid: true}, {}, {String: "", Valid: true}}, ss)
+}
+
+func TestRows_ConvertValueError(t *testing.T) {
+	c := createConnectionFixture()
+	db := sql.OpenDB(fixtureConnector{conn: c})
+	defer db.Close()
+
+	rows, err := db.Query("SELECT_PARSE_ERROR")
+	assert.Nil(t, err)
+	defer rows.Close()
+	var amounts []int64
+	for rows.Next() {
+		var amount int64
+		assert.Nil(t, rows.Scan(&amount))
+		amounts = append(amounts, amount)
+	}
+	assert.Equal(t, []int64{12}, amounts)
+	err = rows.Err()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "column amount (bigint)")
+	var numErr *strconv.NumError
+	assert.True(t, errors.As(err, &numErr))
+}
@@ -23,7 +23,6 @@ package athenadriver
 import (
 	"context"
 	"database/sql/driver"
-	"strings"
 )
 
 // Statement is to implement Go's database/sql Statement.
@@ -55,7 +54,7 @@ func (s *Statement) Close() error {
 // -- From Go `sql/driver`
 func (s *Statement) NumInput() int {
 	if s.numInput == 0 {
-		s.numInput = strings.Count(s.query, "?")
+		s.numInput = countPlaceholders(s.query)
 	}
 	return s.numInput
 }
@@ -47,6 +47,13 @@ func TestStatement_NumInput(t *testing.T) {
 	assert.NotNil(t, e)
 	assert.Nil(t, r)
 	assert.Equal(t, st.NumInput(), 1)
+
+	stmt, err := conn.Prepare("SELECT 'what?' FROM t WHERE a = ? AND b = ?")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, stmt.NumInput())
+	stmt, err = conn.Prepare("SELECT 'what? FROM t WHERE a = ?")
+	assert.Nil(t, err)
+	assert.Equal(t, -1, stmt.NumInput())
 }
 
 func TestStatement_Exec(t *testing.T) {
@@ -0,0 +1,119 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package athenadriver
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
+)
+
+// QueryStatus is a snapshot of the status of a query execution.
+type QueryStatus struct {
+	QueryID string
+	// State is one of QUEUED, RUNNING, SUCCEEDED, FAILED and CANCELLED.
+	State             string
+	StateChangeReason string
+	// Elapsed is the time since the query was submitted.
+	Elapsed            time.Duration
+	DataScannedInBytes int64
+	// Err is the error of getting the status, which is the last one sent to the channel.
+	Err error
+}
+
+// IsTerminal is to check if the query is in SUCCEEDED, FAILED or CANCELLED state.
+func (s QueryStatus) IsTerminal() bool {
+	switch s.State {
+	case athena.QueryExecutionStateSucceeded, athena.QueryExecutionStateFailed,
+		athena.QueryExecutionStateCancelled:
+		return true
+	}
+	return false
+}
+
+// QueryStatusChan is to poll the status of a query every PoolInterval seconds and send it to the returned
+// channel, until the query is in a terminal state, getting its status fails, or ctx is done. The channel is
+// closed then. Use it with sql.Conn.Raw() like:
+//   conn.Raw(func(driverConn interface{}) error {
+//       for status := range driverConn.(*athenadriver.Connection).QueryStatusChan(ctx, queryID) {
+//           ...
+//       }
+//       return nil
+//   })
+func (c *Connection) QueryStatusChan(ctx context.Context, queryID string) <-chan QueryStatus {
+	ch := make(chan QueryStatus)
+	athenaAPI := c.athenaAPI
+	start := time.Now()
+	go func() {
+		defer close(ch)
+		for {
+			status := getQueryStatus(ctx, athenaAPI, queryID, start)
+			select {
+			case ch <- status:
+			case <-ctx.Done():
+				return
+			}
+			if status.Err != nil || status.IsTerminal() {
+				return
+			}
+			select {
+			case <-time.After(PoolInterval * time.Second):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// getQueryStatus is to get the status of a query. Elapsed is counted from start if Athena
+// doesn't report the submission time.
+func getQueryStatus(ctx context.Context, athenaAPI athenaiface.AthenaAPI, queryID string,
+	start time.Time) QueryStatus {
+	status := QueryStatus{QueryID: queryID}
+	resp, err := athenaAPI.GetQueryExecutionWithContext(ctx, &athena.GetQueryExecutionInput{
+		QueryExecutionId: aws.String(queryID),
+	})
+	if err != nil {
+		status.Err = err
+		status.Elapsed = time.Since(start)
+		return status
+	}
+	if resp.QueryExecution == nil || resp.QueryExecution.Status == nil {
+		status.Elapsed = time.Since(start)
+		return status
+	}
+	s := resp.QueryExecution.Status
+	status.State = aws.StringValue(s.State)
+	status.StateChangeReason = aws.StringValue(s.StateChangeReason)
+	if s.SubmissionDateTime != nil {
+		status.Elapsed = time.Since(*s.SubmissionDateTime)
+	} else {
+		status.Elapsed = time.Since(start)
+	}
+	if resp.QueryExecution.Statistics != nil {
+		status.DataScannedInBytes = aws.Int64Value(resp.QueryExecution.Statistics.DataScannedInBytes)
+	}
+	return status
+}
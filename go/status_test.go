@@ -0,0 +1,105 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package athenadriver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/stretchr/testify/assert"
+)
+
+func collectQueryStatus(ch <-chan QueryStatus) []QueryStatus {
+	var statuses []QueryStatus
+	for status := range ch {
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func TestConnection_QueryStatusChan(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+
+	statuses := collectQueryStatus(c.QueryStatusChan(context.Background(), "QUEUED_RUNNING_SUCCEEDED_QID"))
+	assert.Len(t, statuses, 3)
+	for i, state := range []string{athena.QueryExecutionStateQueued, athena.QueryExecutionStateRunning,
+		athena.QueryExecutionStateSucceeded} {
+		assert.Equal(t, "QUEUED_RUNNING_SUCCEEDED_QID", statuses[i].QueryID)
+		assert.Equal(t, state, statuses[i].State)
+		assert.Equal(t, int64(i*100), statuses[i].DataScannedInBytes)
+		assert.True(t, statuses[i].Elapsed >= time.Minute)
+		assert.Nil(t, statuses[i].Err)
+	}
+	assert.False(t, statuses[1].IsTerminal())
+	assert.True(t, statuses[2].IsTerminal())
+
+	statuses = collectQueryStatus(c.QueryStatusChan(context.Background(), "SELECTQueryContext_AWS_FAIL_QID"))
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, athena.QueryExecutionStateFailed, statuses[0].State)
+	assert.NotEmpty(t, statuses[0].StateChangeReason)
+
+	statuses = collectQueryStatus(c.QueryStatusChan(context.Background(), "QueryExecutionStateFailed_QID"))
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, ErrTestMockFailedByAthena, statuses[0].Err)
+}
+
+func TestConnection_QueryStatusChanCancel(t *testing.T) {
+	t.Parallel()
+	c := createConnectionFixture()
+
+	// the query stays in QUEUED, so the goroutine only stops when ctx is done
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := c.QueryStatusChan(ctx, "SELECTQueryContext_CANCEL_OK_QID")
+	status := <-ch
+	assert.Equal(t, athena.QueryExecutionStateQueued, status.State)
+	assert.Equal(t, int64(123), status.DataScannedInBytes)
+	cancel()
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		assert.Fail(t, "channel is not closed after ctx is cancelled")
+	}
+
+	// nobody receives
+	ctx, cancel = context.WithCancel(context.Background())
+	ch = c.QueryStatusChan(ctx, "SELECTQueryContext_CANCEL_OK_QID")
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	_, ok := <-ch
+	assert.False(t, ok)
+}
+
+func TestGetQueryStatus(t *testing.T) {
+	start := time.Now()
+	status := getQueryStatus(context.Background(), newMockAthenaClient(), "SELECTQueryContext_OK_QID", start)
+	assert.Equal(t, athena.QueryExecutionStateSucceeded, status.State)
+	assert.Equal(t, int64(0), status.DataScannedInBytes)
+	assert.True(t, status.Elapsed >= 0)
+
+	status = getQueryStatus(context.Background(), newMockAthenaClient(), "UNKNOWN_QID", start)
+	assert.Equal(t, ErrTestMockGeneric, status.Err)
+	assert.Equal(t, "", status.State)
+	assert.False(t, status.IsTerminal())
+}
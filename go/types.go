@@ -0,0 +1,118 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package athenadriver
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	typeOfInt8    = reflect.TypeOf(int8(0))
+	typeOfInt16   = reflect.TypeOf(int16(0))
+	typeOfInt32   = reflect.TypeOf(int32(0))
+	typeOfInt64   = reflect.TypeOf(int64(0))
+	typeOfFloat32 = reflect.TypeOf(float32(0))
+	typeOfFloat64 = reflect.TypeOf(float64(0))
+	typeOfBool    = reflect.TypeOf(false)
+	typeOfString  = reflect.TypeOf("")
+	typeOfBytes   = reflect.TypeOf([]byte(nil))
+	typeOfTime    = reflect.TypeOf(time.Time{})
+)
+
+// athenaGoTypes is the mapping from Athena data type to the Go type a column of it can be scanned into.
+// https://docs.aws.amazon.com/athena/latest/ug/data-types.html
+var athenaGoTypes = map[string]reflect.Type{
+	"tinyint":                  typeOfInt8,
+	"smallint":                 typeOfInt16,
+	"integer":                  typeOfInt32,
+	"bigint":                   typeOfInt64,
+	"float":                    typeOfFloat32,
+	"real":                     typeOfFloat32,
+	"double":                   typeOfFloat64,
+	"boolean":                  typeOfBool,
+	"json":                     typeOfBytes,
+	"varbinary":                typeOfBytes,
+	"binary":                   typeOfBytes,
+	"char":                     typeOfString,
+	"varchar":                  typeOfString,
+	"string":                   typeOfString,
+	"row":                      typeOfString,
+	"struct":                   typeOfString,
+	"array":                    typeOfString,
+	"map":                      typeOfString,
+	"decimal":                  typeOfString,
+	"interval year to month":   typeOfString,
+	"interval day to second":   typeOfString,
+	"ipaddress":                typeOfString,
+	"unknown":                  typeOfString,
+	"date":                     typeOfTime,
+	"time":                     typeOfTime,
+	"time with time zone":      typeOfTime,
+	"timestamp":                typeOfTime,
+	"timestamp with time zone": typeOfTime,
+}
+
+// goAthenaTypes is the mapping from Go type to Athena data type in DDL.
+var goAthenaTypes = map[reflect.Type]string{
+	typeOfInt8:    "tinyint",
+	typeOfInt16:   "smallint",
+	typeOfInt32:   "integer",
+	typeOfInt64:   "bigint",
+	typeOfFloat32: "float",
+	typeOfFloat64: "double",
+	typeOfBool:    "boolean",
+	typeOfString:  "string",
+	typeOfBytes:   "binary",
+	typeOfTime:    "timestamp",
+}
+
+// AthenaTypeToGoType is to get the Go type a column of Athena data type can be scanned into, which is
+// the same type athenadriver converts it to. Parameterized types like `decimal(10,2)`, `varchar(255)`
+// or `array<string>` are mapped by their base type. Complex types like array, map and struct are
+// returned as their string representation, so they are mapped to string.
+func AthenaTypeToGoType(athenaType string) (reflect.Type, error) {
+	t := strings.ToLower(strings.TrimSpace(athenaType))
+	if i := strings.IndexAny(t, "(<"); i != -1 {
+		t = strings.TrimSpace(t[:i])
+	}
+	if goType, ok := athenaGoTypes[t]; ok {
+		return goType, nil
+	}
+	return nil, fmt.Errorf("unknown Athena type `%s`", athenaType)
+}
+
+// GoTypeToAthenaType is the inverse of AthenaTypeToGoType, to get the Athena data type for a Go type.
+// Pointer types are mapped by their element type.
+func GoTypeToAthenaType(goType reflect.Type) (string, error) {
+	if goType == nil {
+		return "", fmt.Errorf("unknown Go type `nil`")
+	}
+	for goType.Kind() == reflect.Ptr {
+		goType = goType.Elem()
+	}
+	if athenaType, ok := goAthenaTypes[goType]; ok {
+		return athenaType, nil
+	}
+	return "", fmt.Errorf("unknown Go type `%s`", goType)
+}
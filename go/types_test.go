@@ -0,0 +1,124 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package athenadriver
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAthenaTypeToGoType(t *testing.T) {
+	expected := map[string]reflect.Type{
+		"tinyint":                   reflect.TypeOf(int8(0)),
+		"smallint":                  reflect.TypeOf(int16(0)),
+		"integer":                   reflect.TypeOf(int32(0)),
+		"bigint":                    reflect.TypeOf(int64(0)),
+		"float":                     reflect.TypeOf(float32(0)),
+		"real":                      reflect.TypeOf(float32(0)),
+		"double":                    reflect.TypeOf(float64(0)),
+		"boolean":                   reflect.TypeOf(true),
+		"varbinary":                 reflect.TypeOf([]byte{}),
+		"json":                      reflect.TypeOf([]byte{}),
+		"varchar":                   reflect.TypeOf(""),
+		"map":                       reflect.TypeOf(""),
+		"timestamp":                 reflect.TypeOf(time.Time{}),
+		"timestamp with time zone":  reflect.TypeOf(time.Time{}),
+		" BIGINT ":                  reflect.TypeOf(int64(0)),
+		"decimal(10,2)":             reflect.TypeOf(""),
+		"array<string>":             reflect.TypeOf(""),
+		"struct<a:int,b:string>":    reflect.TypeOf(""),
+		"interval day to second":    reflect.TypeOf(""),
+		"time with time zone":       reflect.TypeOf(time.Time{}),
+		"varchar(255)":              reflect.TypeOf(""),
+		"interval year to month":    reflect.TypeOf(""),
+		"unknown":                   reflect.TypeOf(""),
+		"ipaddress":                 reflect.TypeOf(""),
+		"date":                      reflect.TypeOf(time.Time{}),
+		"char(1)":                   reflect.TypeOf(""),
+		"binary":                    reflect.TypeOf([]byte{}),
+		"row(a integer, b varchar)": reflect.TypeOf(""),
+	}
+	for athenaType, goType := range expected {
+		actual, err := AthenaTypeToGoType(athenaType)
+		assert.Nil(t, err, athenaType)
+		assert.Equal(t, goType, actual, athenaType)
+	}
+
+	// all types supported by the driver are mapped
+	types := []string{"tinyint", "smallint", "integer", "bigint", "float", "real", "double",
+		"json", "char", "varchar", "varbinary", "row", "string", "binary",
+		"struct", "interval year to month", "interval day to second", "decimal",
+		"ipaddress", "array", "map", "unknown", "boolean", "date", "time", "time with time zone",
+		"timestamp with time zone", "timestamp"}
+	for _, ty := range types {
+		_, err := AthenaTypeToGoType(ty)
+		assert.Nil(t, err, ty)
+	}
+
+	goType, err := AthenaTypeToGoType("weird_type")
+	assert.Nil(t, goType)
+	assert.NotNil(t, err)
+}
+
+func TestAthenaTypeToGoType_MatchesRows(t *testing.T) {
+	testConf := NewNoOpsConfig()
+	r, _ := NewRows(context.Background(), newMockAthenaClient(),
+		"SELECT_OK", testConf, newDefaultObservability(testConf))
+	types := []string{"tinyint", "smallint", "integer", "bigint", "float", "real", "double",
+		"json", "char", "varchar", "varbinary", "row", "string", "binary",
+		"struct", "interval year to month", "interval day to second", "decimal",
+		"ipaddress", "array", "map", "unknown", "boolean", "date", "time", "time with time zone",
+		"timestamp with time zone", "timestamp"}
+	for _, ty := range types {
+		c := newColumnInfo("a", ty)
+		row := randRow([]*athena.ColumnInfo{c})
+		value, err := r.athenaTypeToGoType(c, row.Data[0].VarCharValue, testConf)
+		assert.Nil(t, err, ty)
+		goType, err := AthenaTypeToGoType(ty)
+		assert.Nil(t, err, ty)
+		assert.Equal(t, goType, reflect.TypeOf(value), ty)
+	}
+}
+
+func TestGoTypeToAthenaType(t *testing.T) {
+	for _, athenaType := range []string{"tinyint", "smallint", "integer", "bigint", "float", "double",
+		"boolean", "string", "binary", "timestamp"} {
+		goType, err := AthenaTypeToGoType(athenaType)
+		assert.Nil(t, err)
+		actual, err := GoTypeToAthenaType(goType)
+		assert.Nil(t, err)
+		assert.Equal(t, athenaType, actual)
+	}
+
+	athenaType, err := GoTypeToAthenaType(reflect.TypeOf(new(int64)))
+	assert.Nil(t, err)
+	assert.Equal(t, "bigint", athenaType)
+
+	_, err = GoTypeToAthenaType(reflect.TypeOf(aType{}))
+	assert.NotNil(t, err)
+	_, err = GoTypeToAthenaType(nil)
+	assert.NotNil(t, err)
+}
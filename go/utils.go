@@ -21,16 +21,25 @@
 package athenadriver
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
 	"database/sql"
 	"database/sql/driver"
 	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/aws/aws-sdk-go/service/athena"
+	"io"
 	"math"
 	"math/rand"
+	"net"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -55,56 +64,324 @@ func mockRowsToSQLRows(mockRows *sqlmock.Rows) *sql.Rows {
 	return rows
 }
 
+// CSVOptions is the options for writing sql.Rows in CSV format.
+type CSVOptions struct {
+	// Delimiter is the field delimiter. ',' is used if it is not set.
+	Delimiter rune
+	// OmitHeader is to skip writing column names as the first record.
+	OmitHeader bool
+	// Gzip is to compress the output with gzip. The gzip stream is always closed, even on error.
+	Gzip bool
+	// UseCRLF is to end each line with \r\n instead of \n.
+	UseCRLF bool
+	// BufferSize is the size in bytes of the buffer before writing to the io.Writer. It can only be
+	// increased from the default 4096 bytes.
+	BufferSize int
+	// FlushInterval is the number of rows between flushing the buffer to the io.Writer, like for a
+	// consumer reading the output as it is written. 0 means to flush only when the buffer is full.
+	FlushInterval int
+}
+
+// CSVOption is a functional option of WriteCSV.
+type CSVOption func(*CSVOptions)
+
+// CSVHeader is to set if column names are written as the first record. The default is true.
+func CSVHeader(include bool) CSVOption {
+	return func(o *CSVOptions) {
+		o.OmitHeader = !include
+	}
+}
+
+// CSVDelimiter is to set the field delimiter. The default is ','.
+func CSVDelimiter(delimiter rune) CSVOption {
+	return func(o *CSVOptions) {
+		o.Delimiter = delimiter
+	}
+}
+
+// CSVCRLF is to set if lines end with \r\n instead of \n. The default is false.
+func CSVCRLF(useCRLF bool) CSVOption {
+	return func(o *CSVOptions) {
+		o.UseCRLF = useCRLF
+	}
+}
+
+// CSVGzip is to set if the output is compressed with gzip. The default is false.
+func CSVGzip(gzip bool) CSVOption {
+	return func(o *CSVOptions) {
+		o.Gzip = gzip
+	}
+}
+
+// CSVBufferSize is to set the size in bytes of the write buffer. The default is 4096.
+func CSVBufferSize(size int) CSVOption {
+	return func(o *CSVOptions) {
+		o.BufferSize = size
+	}
+}
+
+// CSVFlushInterval is to set the number of rows between flushes of the write buffer. The default is 0, which
+// means to flush only when the buffer is full.
+func CSVFlushInterval(rows int) CSVOption {
+	return func(o *CSVOptions) {
+		o.FlushInterval = rows
+	}
+}
+
+// WriteCSV is to write columns and rows of sql.Rows to w in CSV format, with a header and ',' as delimiter
+// by default. Rows are streamed through a bounded buffer, so memory use doesn't grow with the result size.
+// Example:
+//   err := athenadriver.WriteCSV(rows, os.Stdout, athenadriver.CSVHeader(false), athenadriver.CSVDelimiter('\t'))
+func WriteCSV(rows *sql.Rows, w io.Writer, opts ...CSVOption) error {
+	var options CSVOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return RowsToCSVWriterWithOptions(rows, w, options)
+}
+
+// RowsToCSVWriter is to write columns and rows of sql.Rows to w in CSV format. Fields are quoted per RFC 4180,
+// so values containing delimiters, quotes or newlines round-trip correctly.
+func RowsToCSVWriter(rows *sql.Rows, w io.Writer) error {
+	return WriteCSV(rows, w)
+}
+
+// RowsToCSVWriterWithOptions is the same as RowsToCSVWriter, but with configurable CSVOptions.
+// NULL values are written as empty fields.
+func RowsToCSVWriterWithOptions(rows *sql.Rows, w io.Writer, opts CSVOptions) (err error) {
+	if rows == nil {
+		return nil
+	}
+	if opts.Gzip {
+		gzipWriter := gzip.NewWriter(w)
+		defer func() {
+			if closeErr := gzipWriter.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = gzipWriter
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	csvWriter := newCSVWriter(w, opts)
+	if !opts.OmitHeader && len(columns) > 0 {
+		if err := csvWriter.Write(columns); err != nil {
+			return err
+		}
+	}
+	rawResult := make([][]byte, len(columns))
+	row := make([]interface{}, len(columns))
+	for i := range rawResult {
+		row[i] = &rawResult[i] // pointers to each string in the interface slice
+	}
+	record := make([]string, len(columns))
+	rowCount := 0
+	for rows.Next() {
+		if err := rows.Scan(row...); err != nil {
+			return err
+		}
+		for i, cell := range rawResult {
+			record[i] = string(cell)
+		}
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+		if rowCount++; opts.FlushInterval > 0 && rowCount%opts.FlushInterval == 0 {
+			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				return err
+			}
+		}
+	}
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return err
+	}
+	return rows.Err()
+}
+
+func newCSVWriter(w io.Writer, opts CSVOptions) *csv.Writer {
+	if opts.BufferSize > 4096 {
+		// csv.Writer reuses a bufio.Writer of at least its default size of 4096 bytes and flushes it, while
+		// a smaller one would be wrapped by another one and never flushed, so it only sets a larger size.
+		w = bufio.NewWriterSize(w, opts.BufferSize)
+	}
+	csvWriter := csv.NewWriter(w)
+	if opts.Delimiter != 0 {
+		csvWriter.Comma = opts.Delimiter
+	}
+	csvWriter.UseCRLF = opts.UseCRLF
+	return csvWriter
+}
+
 // ColsToCSV is a convenient function to convert columns of sql.Rows to CSV format.
 func ColsToCSV(rows *sql.Rows) string {
 	if rows == nil {
 		return ""
 	}
 	columns, _ := rows.Columns()
-	s := ""
-	for i, v := range columns {
-		s += v
-		if i != len(columns)-1 {
-			s += ","
-		} else {
-			s += "\n"
-		}
+	if len(columns) == 0 {
+		return ""
 	}
-	return s
+	var buf bytes.Buffer
+	csvWriter := newCSVWriter(&buf, CSVOptions{})
+	_ = csvWriter.Write(columns)
+	csvWriter.Flush()
+	return buf.String()
 }
 
 // RowsToCSV is to convert rows of sql.Rows to CSV format.
+// The whole output is kept in memory, so use WriteCSV for large results instead.
 func RowsToCSV(rows *sql.Rows) string {
+	var buf bytes.Buffer
+	// We don't consider malformed rows
+	_ = WriteCSV(rows, &buf, CSVHeader(false))
+	return buf.String()
+}
+
+// ColsRowsToCSV is a convenient function to convert columns and rows of sql.Rows to CSV format.
+// The whole output is kept in memory, so use WriteCSV for large results instead.
+func ColsRowsToCSV(rows *sql.Rows) string {
+	var buf bytes.Buffer
+	_ = WriteCSV(rows, &buf)
+	return buf.String()
+}
+
+// scannedString is to get the string of src a sql.Scanner gets for an Athena column, which is []byte or string.
+// ok is false for NULL. An error is returned for any other type, which can't be converted to goType.
+func scannedString(src interface{}, goType string) (s string, ok bool, err error) {
+	switch v := src.(type) {
+	case nil:
+		return "", false, nil
+	case []byte:
+		return string(v), true, nil
+	case string:
+		return v, true, nil
+	default:
+		return "", false, fmt.Errorf("cannot convert %v (%T) to %s", src, src, goType)
+	}
+}
+
+// RowsToMaps is to convert rows of sql.Rows to a slice of maps from column name to value, like for JSON APIs.
+// Values are converted by athenadriver as in Scan into interface{}, so numbers come out as int64 or float64,
+// booleans as bool and NULL as nil. json columns come out as json.RawMessage, so they are embedded as they
+// are by encoding/json. A duplicate column name gets suffix _1, _2 and so on in order.
+func RowsToMaps(rows *sql.Rows) ([]map[string]interface{}, error) {
 	if rows == nil {
-		return ""
+		return nil, nil
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	isJSON := make([]bool, len(columnTypes))
+	for i, columnType := range columnTypes {
+		isJSON[i] = strings.EqualFold(columnType.DatabaseTypeName(), "json")
+	}
+	keys := uniqueColumnNames(columns)
+	result := []map[string]interface{}{}
+	values := make([]interface{}, len(columns))
+	row := make([]interface{}, len(columns))
+	for i := range values {
+		row[i] = &values[i]
 	}
-	columns, _ := rows.Columns()
-	var buf bytes.Buffer
-	csvWriter := csv.NewWriter(&buf)
-	records := make([][]string, 0)
 	for rows.Next() {
-		rawResult := make([][]byte, len(columns))
-		row := make([]interface{}, len(columns))
-		for i := range rawResult {
-			row[i] = &rawResult[i] // pointers to each string in the interface slice
+		if err := rows.Scan(row...); err != nil {
+			return nil, err
 		}
-		// We don't consider malformed rows
-		_ = rows.Scan(row...)
-		s := make([]string, len(columns))
-		for i, cell := range rawResult {
-			s[i] = string(cell)
+		m := make(map[string]interface{}, len(keys))
+		for i, key := range keys {
+			if b, ok := values[i].([]byte); ok && isJSON[i] {
+				m[key] = json.RawMessage(b)
+				continue
+			}
+			m[key] = values[i]
 		}
-		records = append(records, s)
+		result = append(result, m)
 	}
-	csvWriter.WriteAll(records)
-	return buf.String()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
-// ColsRowsToCSV is a convenient function to convert columns and rows of sql.Rows to CSV format.
-func ColsRowsToCSV(rows *sql.Rows) string {
-	s := ColsToCSV(rows)
-	r := RowsToCSV(rows)
-	return s + r
+// uniqueColumnNames is to disambiguate duplicate column names by appending _1, _2, etc. to the later ones,
+// skipping any suffixed name already used by another column.
+func uniqueColumnNames(columns []string) []string {
+	used := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		used[c] = true
+	}
+	seen := make(map[string]bool, len(columns))
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		name := c
+		// a suffixed name can't take the name of another column
+		for n := 1; seen[name] || (name != c && used[name]); n++ {
+			name = c + "_" + strconv.Itoa(n)
+		}
+		seen[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+// timeValue is to get the value of a time pointer of Athena Go SDK. The bool is false if it is nil.
+func timeValue(t *time.Time) (time.Time, bool) {
+	if t == nil {
+		return time.Time{}, false
+	}
+	return *t, true
+}
+
+// parseS3URI is to split an S3 URI like s3://bucket/prefix/key into bucket and key.
+func parseS3URI(uri string) (string, string, bool) {
+	if !strings.HasPrefix(uri, "s3://") {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(uri, "s3://"), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// outputPrefixTokens are the date tokens of OutputPrefixTemplate.
+var outputPrefixTokens = []string{"{yyyy}", "2006", "{mm}", "01", "{dd}", "02", "{hh}", "15"}
+
+// reS3KeyPrefix is the pattern of S3 key prefixes with only characters safe to use.
+// https://docs.aws.amazon.com/AmazonS3/latest/dev/UsingMetadata.html#object-key-guidelines
+var reS3KeyPrefix = regexp.MustCompile(`^[a-zA-Z0-9!_.*'()=/-]+$`)
+
+// expandOutputPrefix is to expand the date tokens in an OutputPrefixTemplate with time t in UTC.
+func expandOutputPrefix(template string, t time.Time) string {
+	pairs := make([]string, len(outputPrefixTokens))
+	for i := 0; i < len(outputPrefixTokens); i += 2 {
+		pairs[i] = outputPrefixTokens[i]
+		pairs[i+1] = t.UTC().Format(outputPrefixTokens[i+1])
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+// isValidOutputPrefixTemplate is to check if the expanded template is a legal S3 key prefix, which has no
+// unknown tokens, empty path segments or `.`/`..` segments, and is not longer than 1024 bytes.
+func isValidOutputPrefixTemplate(template string) bool {
+	prefix := expandOutputPrefix(template, time.Now())
+	if len(prefix) > 1024 || !reS3KeyPrefix.MatchString(prefix) {
+		return false
+	}
+	for _, segment := range strings.Split(strings.TrimSuffix(prefix, "/"), "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
 }
 
 // colInFirstPage is to check if this is a SELECT or VALUES statement.
@@ -131,25 +408,266 @@ func ColsRowsToCSV(rows *sql.Rows) string {
 // )
 // SELECT concat(words, alexa) AS welcome_msg FROM dataset
 func colInFirstPage(query string) bool {
-	nQuery := strings.TrimSpace(strings.ToLower(query))
-	return strings.Index(nQuery, "select") == 0 ||
-		strings.Index(nQuery, "using") == 0 ||
-		strings.Index(nQuery, "with") == 0 ||
-		strings.Index(nQuery, "values") == 0
+	switch leadingKeyword(query) {
+	case "select", "using", "with", "values":
+		return true
+	}
+	return false
 }
 
+// isUtilityStatement is to check if this is a DESCRIBE, SHOW or EXPLAIN statement.
+func isUtilityStatement(query string) bool {
+	switch leadingKeyword(query) {
+	case "desc", "describe", "show", "explain":
+		return true
+	}
+	return false
+}
+
+// isReadOnlyStatement is to check if this is a SELECT, VALUES, DESCRIBE, SHOW or EXPLAIN statement, which
+// doesn't write anything. EXPLAIN ANALYZE runs the statement it explains, so that statement must be read-only too.
 func isReadOnlyStatement(query string) bool {
-	nQuery := strings.TrimSpace(strings.ToLower(query))
-	return strings.Index(nQuery, "select") == 0 ||
-		strings.Index(nQuery, "using") == 0 ||
-		strings.Index(nQuery, "with") == 0 ||
-		strings.Index(nQuery, "desc") == 0 ||
-		strings.Index(nQuery, "show") == 0
+	keyword, end := nextKeyword(query, 0)
+	switch keyword {
+	case "select", "values", "using", "with", "desc", "describe", "show":
+		return true
+	case "explain":
+		if keyword, end = nextKeyword(query, end); keyword != "analyze" {
+			return true
+		}
+		if keyword, next := nextKeyword(query, end); keyword == "verbose" {
+			end = next
+		}
+		return isReadOnlyStatement(query[end:])
+	}
+	return false
 }
 
 func isInsertStatement(query string) bool {
-	nQuery := strings.TrimSpace(strings.ToLower(query))
-	return strings.Index(nQuery, "insert") == 0
+	return leadingKeyword(query) == "insert"
+}
+
+// isWriteDMLStatement is to check if this is a DML statement writing data, i.e. INSERT, or MERGE, UPDATE
+// and DELETE for Iceberg tables. Athena reports the number of rows written as UpdateCount.
+func isWriteDMLStatement(query string) bool {
+	switch leadingKeyword(query) {
+	case "insert", "merge", "update", "delete":
+		return true
+	}
+	return false
+}
+
+// updateType is to get the type of a statement writing data the same as UpdateType of Athena, i.e. INSERT,
+// UPDATE, DELETE, MERGE or CREATE TABLE AS SELECT. It returns an empty string for other statements.
+func updateType(query string) string {
+	words := topLevelWords(query)
+	if len(words) == 0 {
+		return ""
+	}
+	switch words[0].word {
+	case "insert", "update", "delete", "merge":
+		return strings.ToUpper(words[0].word)
+	case "create":
+		if len(words) < 3 || words[1].word != "table" {
+			return ""
+		}
+		for _, w := range words[2:] {
+			if w.word == "as" {
+				return "CREATE TABLE AS SELECT"
+			}
+		}
+	}
+	return ""
+}
+
+// statementType is to classify query as athena.StatementTypeDml, athena.StatementTypeDdl or
+// athena.StatementTypeUtility by its leading keyword, the same way Athena reports StatementType.
+// It returns an empty string if the query doesn't start with a keyword.
+func statementType(query string) string {
+	if colInFirstPage(query) || isWriteDMLStatement(query) {
+		return athena.StatementTypeDml
+	}
+	if isUtilityStatement(query) {
+		return athena.StatementTypeUtility
+	}
+	if leadingKeyword(query) == "" {
+		return ""
+	}
+	return athena.StatementTypeDdl
+}
+
+// leadingKeyword is to get the first keyword of query in lower case, skipping leading whitespaces,
+// parentheses, line comments and block comments, like `select` in:
+//   -- daily report
+//   /* owner: data team */ (SELECT * FROM t)
+func leadingKeyword(query string) string {
+	keyword, _ := nextKeyword(query, 0)
+	return keyword
+}
+
+// nextKeyword is to get the first keyword of query from index i in lower case and the index after it,
+// skipping the same as leadingKeyword.
+func nextKeyword(query string, i int) (string, int) {
+	for {
+		t, ok := nextSQLToken(query, i)
+		switch {
+		case !ok || t.kind == sqlTokenEOF:
+			return "", len(query)
+		case t.kind == sqlTokenSymbol && query[t.start] == '(':
+			i = t.end
+		case t.kind == sqlTokenWord && isKeywordChar(query[t.start]):
+			return strings.ToLower(query[t.start:t.end]), t.end
+		default:
+			return "", t.start
+		}
+	}
+}
+
+func isKeywordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isWordChar(c byte) bool {
+	return isKeywordChar(c) || c >= '0' && c <= '9'
+}
+
+type sqlTokenKind int
+
+const (
+	sqlTokenEOF sqlTokenKind = iota
+	// sqlTokenWord is a keyword, an identifier or a number
+	sqlTokenWord
+	// sqlTokenQuoted is a string literal or a quoted identifier, with its quotes
+	sqlTokenQuoted
+	// sqlTokenSymbol is any other single byte, like `(`, `;` or `?`
+	sqlTokenSymbol
+)
+
+// sqlToken is a token of a query at [start, end). Whitespaces and comments are not tokens.
+type sqlToken struct {
+	kind       sqlTokenKind
+	start, end int
+}
+
+// nextSQLToken is to get the token of query from index i. It is the only lexer of athenadriver, so all
+// analysis of queries agrees on what is in string literals, quoted identifiers and comments:
+//   - a string literal or quoted identifier is in `'`, `"` or `` ` ``, and the quote inside is doubled
+//     like 'what''s up?'
+//   - a line comment starts with `--` and ends at `\n` or `\r` like in Athena
+//   - a block comment is in `/*` and `*/`
+// It returns false if query has an unterminated quote or block comment.
+func nextSQLToken(query string, i int) (sqlToken, bool) {
+	for i < len(query) {
+		switch c := query[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexAny(query[i:], "\r\n")
+			if end == -1 {
+				return sqlToken{kind: sqlTokenEOF, start: len(query), end: len(query)}, true
+			}
+			i += end + 1
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end == -1 {
+				return sqlToken{}, false
+			}
+			i += end + 4
+		case c == '\'' || c == '"' || c == '`':
+			j := i + 1
+			for ; j < len(query); j++ {
+				if query[j] != c {
+					continue
+				}
+				if j+1 < len(query) && query[j+1] == c {
+					j++
+					continue
+				}
+				return sqlToken{kind: sqlTokenQuoted, start: i, end: j + 1}, true
+			}
+			return sqlToken{}, false
+		case isWordChar(c):
+			j := i + 1
+			for j < len(query) && isWordChar(query[j]) {
+				j++
+			}
+			return sqlToken{kind: sqlTokenWord, start: i, end: j}, true
+		default:
+			return sqlToken{kind: sqlTokenSymbol, start: i, end: i + 1}, true
+		}
+	}
+	return sqlToken{kind: sqlTokenEOF, start: len(query), end: len(query)}, true
+}
+
+// sqlTokens is to get the tokens of query. It returns false with the tokens before if query has an
+// unterminated quote or block comment.
+func sqlTokens(query string) ([]sqlToken, bool) {
+	var tokens []sqlToken
+	for i := 0; ; {
+		t, ok := nextSQLToken(query, i)
+		if !ok {
+			return tokens, false
+		}
+		if t.kind == sqlTokenEOF {
+			return tokens, true
+		}
+		tokens = append(tokens, t)
+		i = t.end
+	}
+}
+
+// placeholderPositions is to find the indexes of `?` placeholders in query, skipping those in string
+// literals, quoted identifiers and comments. Quotes inside a literal are escaped by doubling them, like
+// 'what''s up?'. It returns false if query can't be analyzed due to unterminated quote or comment.
+func placeholderPositions(query string) ([]int, bool) {
+	return topLevelPositions(query, '?')
+}
+
+// topLevelPositions is to find the indexes of byte target in query which are not in string literals,
+// quoted identifiers or comments. It returns false if query has unterminated quote or block comment.
+func topLevelPositions(query string, target byte) ([]int, bool) {
+	tokens, ok := sqlTokens(query)
+	if !ok {
+		return nil, false
+	}
+	var positions []int
+	for _, t := range tokens {
+		if t.kind == sqlTokenSymbol && query[t.start] == target {
+			positions = append(positions, t.start)
+		}
+	}
+	return positions, true
+}
+
+// splitStatements is to split a script into statements by top level semicolons, which are not in
+// string literals, quoted identifiers or comments. Empty statements and statements of only comments
+// are dropped. It returns false if script has unterminated quote or block comment.
+func splitStatements(script string) ([]string, bool) {
+	positions, ok := topLevelPositions(script, ';')
+	if !ok {
+		return nil, false
+	}
+	var statements []string
+	last := 0
+	for _, pos := range append(positions, len(script)) {
+		statement := strings.TrimSpace(script[last:pos])
+		last = pos + 1
+		if leadingKeyword(statement) == "" {
+			continue
+		}
+		statements = append(statements, statement)
+	}
+	return statements, true
+}
+
+// countPlaceholders is to count `?` placeholders in query as placeholderPositions does.
+// It returns -1 if query can't be analyzed.
+func countPlaceholders(query string) int {
+	positions, ok := placeholderPositions(query)
+	if !ok {
+		return -1
+	}
+	return len(positions)
 }
 
 func newColumnInfo(colName string, colType interface{}) *athena.ColumnInfo {
@@ -228,8 +746,8 @@ func randInt() *string {
 	return &s
 }
 
-func randUInt64() *string {
-	s := strconv.FormatUint(rand.Uint64(), 10)
+func randInt64() *string {
+	s := strconv.FormatInt(rand.Int63(), 10)
 	return &s
 }
 
@@ -275,6 +793,31 @@ func randTimeStamp() *string {
 	return &s
 }
 
+func randIntervalYearToMonth() *string {
+	s := Interval{Months: randomInt64(-1200, 1200)}.String()
+	return &s
+}
+
+func randIntervalDayToSecond() *string {
+	nanos := randomInt64(0, int64(24*time.Hour)) / int64(time.Millisecond) * int64(time.Millisecond)
+	iv := Interval{Days: randomInt64(0, 1000), Nanos: nanos}
+	if rand.Intn(2) == 0 {
+		iv.Days, iv.Nanos = -iv.Days, -iv.Nanos
+	}
+	s := iv.String()
+	return &s
+}
+
+func randIPAddress() *string {
+	ip := make(net.IP, net.IPv6len)
+	rand.Read(ip)
+	if rand.Intn(2) == 0 {
+		ip = ip[:net.IPv4len]
+	}
+	s := ip.String()
+	return &s
+}
+
 func genHeaderRow(columns []*athena.ColumnInfo) *athena.Row {
 	colLen := len(columns)
 	rData := make([]string, colLen)
@@ -305,15 +848,20 @@ func randRow(columns []*athena.ColumnInfo) *athena.Row {
 		case "integer":
 			row.Data[j] = &athena.Datum{VarCharValue: randInt()}
 		case "bigint":
-			row.Data[j] = &athena.Datum{VarCharValue: randUInt64()}
+			row.Data[j] = &athena.Datum{VarCharValue: randInt64()}
 		case "float", "real":
 			row.Data[j] = &athena.Datum{VarCharValue: randFloat32()}
 		case "double":
 			row.Data[j] = &athena.Datum{VarCharValue: randFloat64()}
 		case "json", "char", "varchar", "varbinary", "row", "string", "binary",
-			"struct", "interval year to month", "interval day to second", "decimal",
-			"ipaddress", "array", "map", "unknown":
+			"struct", "decimal", "array", "map", "unknown":
 			row.Data[j] = &athena.Datum{VarCharValue: randStr()}
+		case "ipaddress":
+			row.Data[j] = &athena.Datum{VarCharValue: randIPAddress()}
+		case "interval year to month":
+			row.Data[j] = &athena.Datum{VarCharValue: randIntervalYearToMonth()}
+		case "interval day to second":
+			row.Data[j] = &athena.Datum{VarCharValue: randIntervalDayToSecond()}
 		case "boolean":
 			row.Data[j] = &athena.Datum{VarCharValue: randBool()}
 		case "date":
@@ -435,6 +983,27 @@ func escapeStringBackslash(buf []byte, v string) []byte {
 	return escapeBytesBackslash(buf, []byte(v))
 }
 
+// escapeStringQuotes escapes a string for a single-quoted Athena string literal by doubling single quotes (').
+// Backslashes have no special meaning in Athena string literals, so they are kept as they are.
+func escapeStringQuotes(buf []byte, v string) []byte {
+	pos := len(buf)
+	buf = reserveBuffer(buf, len(v)*2)
+
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		if c == '\'' {
+			buf[pos] = '\''
+			buf[pos+1] = '\''
+			pos += 2
+		} else {
+			buf[pos] = c
+			pos++
+		}
+	}
+
+	return buf[:pos]
+}
+
 // reserveBuffer checks cap(buf) and expand buffer to len(buf) + appendSize.
 // If cap(buf) is not enough, reallocate new buffer.
 func reserveBuffer(buf []byte, appendSize int) []byte {
@@ -465,31 +1034,175 @@ func valueToNamedValue(args []driver.Value) []driver.NamedValue {
 	return nameValues
 }
 
-func isQueryTimeOut(startOfStartQueryExecution time.Time, queryType string) bool {
+// queryTimeout is to get the statement timeout of a query by its type, as configured in config.
+func queryTimeout(queryType string, config *Config) time.Duration {
 	switch queryType {
 	case "DDL":
-		return time.Since(startOfStartQueryExecution) >
-			DDLQueryTimeout*time.Second
+		return config.GetDDLQueryTimeout()
 	case "DML":
-		return time.Since(startOfStartQueryExecution) >
-			DMLQueryTimeout*time.Second
+		return config.GetDMLQueryTimeout()
 	case "UTILITY":
-		return time.Since(startOfStartQueryExecution) >
-			DMLQueryTimeout*time.Second
+		return config.GetDMLQueryTimeout()
 	case "TIMEOUT_NOW":
-		return true
+		return 0
 	default:
-		return time.Since(startOfStartQueryExecution) >
-			DDLQueryTimeout*time.Second
+		return config.GetDDLQueryTimeout()
 	}
 }
 
+func isQueryTimeOut(startOfStartQueryExecution time.Time, queryType string, config *Config) bool {
+	return time.Since(startOfStartQueryExecution) > queryTimeout(queryType, config)
+}
+
+// queryDeadline is to get the effective deadline of a query, which is the earlier one of its statement
+// timeout and the deadline of ctx. The returned bool is true if the deadline of ctx is the limiting factor.
+func queryDeadline(ctx context.Context, startOfStartQueryExecution time.Time, queryType string,
+	config *Config) (time.Time, bool) {
+	deadline := startOfStartQueryExecution.Add(queryTimeout(queryType, config))
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		return ctxDeadline, true
+	}
+	return deadline, false
+}
+
 // isQueryValid is to check the validity of Query, now only string length check.
 // https://docs.aws.amazon.com/athena/latest/ug/service-limits.html
 func isQueryValid(query string) bool {
 	return len(query) < MAXQueryStringLength
 }
 
+// getClientRequestToken is to get the ClientRequestToken of a query from ctx. It is either set explicitly
+// with ClientRequestTokenKey, or derived from IdempotencyKey as the hex encoded SHA-256 of the key and
+// the query string. It returns nil if neither is set, and Athena will generate one.
+func getClientRequestToken(ctx context.Context, query string) *string {
+	if token, ok := ctx.Value(ClientRequestTokenKey).(string); ok && token != "" {
+		return &token
+	}
+	if key, ok := ctx.Value(IdempotencyKey).(string); ok && key != "" {
+		h := sha256.New()
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write([]byte(query))
+		token := hex.EncodeToString(h.Sum(nil))
+		return &token
+	}
+	return nil
+}
+
+// retryClientRequestToken is to derive the ClientRequestToken of the attempt-th retry of a query from its
+// token, so the retry is a new query execution in Athena, while retries of the same query by different
+// clients still get the same token. It returns nil if the query has no token.
+func retryClientRequestToken(token *string, attempt int) *string {
+	if token == nil {
+		return nil
+	}
+	h := sha256.New()
+	h.Write([]byte(*token))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.Itoa(attempt)))
+	retryToken := hex.EncodeToString(h.Sum(nil))
+	return &retryToken
+}
+
+// isOutputAccessDenied is to check if Athena failed a query because it couldn't write to the output location
+// in S3. The StateChangeReason looks like:
+//   Access denied when writing output to url: s3://bucket/prefix/1e2e1ec4.csv . Please ensure you are allowed
+//   to access the S3 bucket. If you are encrypting query results with KMS key, please ensure you are allowed
+//   to access your KMS key
+func isOutputAccessDenied(reason string) bool {
+	return strings.Contains(strings.ToLower(reason), "access denied when writing output to url")
+}
+
+// isBytesScannedCutoff is to check if Athena terminated a query for exceeding the
+// BytesScannedCutoffPerQuery of its workgroup. The StateChangeReason looks like:
+//   Query cancelled! : Bytes scanned limit was exceeded
+func isBytesScannedCutoff(reason string) bool {
+	return strings.Contains(strings.ToLower(reason), "bytes scanned limit")
+}
+
+// isUnsupportedInsertFormat is to check if Athena failed a query because it can't write to the storage
+// format of the table. The StateChangeReason looks like:
+//   HIVE_UNSUPPORTED_FORMAT: Output format org.apache.hadoop.hive.ql.io.avro.AvroContainerOutputFormat with
+//   SerDe org.apache.hadoop.hive.serde2.avro.AvroSerDe is not supported.
+func isUnsupportedInsertFormat(reason string) bool {
+	r := strings.ToLower(reason)
+	if strings.Contains(r, "hive_unsupported_format") {
+		return true
+	}
+	return strings.Contains(r, "not supported") && (strings.Contains(r, "format") || strings.Contains(r, "serde"))
+}
+
+// userErrorNames are the names of the errors caused by queries, from the error codes of Presto and Trino.
+var userErrorNames = map[string]bool{
+	"SYNTAX_ERROR": true, "NOT_SUPPORTED": true, "TYPE_MISMATCH": true, "INVALID_FUNCTION_ARGUMENT": true,
+	"FUNCTION_NOT_FOUND": true, "DIVISION_BY_ZERO": true, "INVALID_CAST_ARGUMENT": true,
+	"NUMERIC_VALUE_OUT_OF_RANGE": true, "INVALID_VIEW": true, "ALREADY_EXISTS": true, "PERMISSION_DENIED": true,
+	"TABLE_NOT_FOUND": true, "COLUMN_NOT_FOUND": true, "SCHEMA_NOT_FOUND": true, "AMBIGUOUS_NAME": true,
+	"INVALID_TABLE_PROPERTY": true, "MISSING_COLUMN_NAME": true, "DUPLICATE_COLUMN_NAME": true,
+	"EXCEEDED_LOCAL_MEMORY_LIMIT": true, "EXCEEDED_TIME_LIMIT": true, "HIVE_BAD_DATA": true,
+	"HIVE_UNSUPPORTED_FORMAT": true, "HIVE_PATH_ALREADY_EXISTS": true,
+}
+
+// systemErrorNames are the names of the errors caused by Athena itself.
+var systemErrorNames = map[string]bool{
+	"GENERIC_INTERNAL_ERROR": true, "INTERNAL_ERROR": true, "SERVER_SHUTTING_DOWN": true,
+	"SERVER_STARTING_UP": true, "TOO_MANY_REQUESTS_FAILED": true, "PAGE_TRANSPORT_TIMEOUT": true,
+	"REMOTE_TASK_ERROR": true, "NO_NODES_AVAILABLE": true, "ICEBERG_COMMIT_ERROR": true,
+}
+
+// errorCategory is to infer the error category of a failed query from its StateChangeReason, which starts
+// with the name of the error, like:
+//   SYNTAX_ERROR: line 1:8: Column 'x' cannot be resolved
+//   GENERIC_INTERNAL_ERROR: Unable to create input format
+// Athena also reports the line and column of a query it can't parse without the name, like:
+//   line 1:8: mismatched input 'FORM'. Expecting: <EOF>
+func errorCategory(reason string) int64 {
+	name := reason
+	if i := strings.IndexByte(reason, ':'); i != -1 {
+		name = reason[:i]
+	}
+	switch name = strings.TrimSpace(name); {
+	case userErrorNames[name] || strings.HasPrefix(name, "line "):
+		return ErrorCategoryUser
+	case systemErrorNames[name]:
+		return ErrorCategorySystem
+	}
+	return ErrorCategoryOther
+}
+
+// insertTableName is to get the table name of an INSERT INTO statement without quotes, like `db.t` in:
+//   INSERT INTO "db"."t" (a, b) SELECT a, b FROM s
+// It returns "" if it is not found.
+func insertTableName(query string) string {
+	tokens, _ := sqlTokens(query)
+	if len(tokens) < 3 || !isWord(query, tokens[0], "insert") || !isWord(query, tokens[1], "into") {
+		return ""
+	}
+	var parts []string
+	for i := 2; i < len(tokens); i += 2 {
+		t := tokens[i]
+		switch {
+		case t.kind == sqlTokenWord:
+			parts = append(parts, query[t.start:t.end])
+		case t.kind == sqlTokenQuoted && query[t.start] != '\'':
+			quote := query[t.start : t.start+1]
+			parts = append(parts, strings.ReplaceAll(query[t.start+1:t.end-1], quote+quote, quote))
+		default:
+			return ""
+		}
+		// dots in quoted identifiers are not separators
+		if i+1 == len(tokens) || tokens[i+1].kind != sqlTokenSymbol || query[tokens[i+1].start] != '.' {
+			break
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// isWord is to check if token t of query is the word, case insensitively.
+func isWord(query string, t sqlToken, word string) bool {
+	return t.kind == sqlTokenWord && strings.EqualFold(query[t.start:t.end], word)
+}
+
 // GetFromEnvVal is to get environmental variable value by keys.
 // The return value is from whichever key is set according to the order in the slice.
 func GetFromEnvVal(keys []string) string {
@@ -501,20 +1214,32 @@ func GetFromEnvVal(keys []string) string {
 	return ""
 }
 
-// printCost is to print query cost
+// CostUSD is to estimate the cost in USD of a query scanning scannedBytes at DefaultPricePerTB. Athena bills
+// at least MinBytesBilled for a query, while a query scanning no data, like a DDL statement, is free.
 // https://aws.amazon.com/athena/pricing/
 // Cost of 10MB: 5 / (1024. * 1024.) * 10 = 4.76837158203125e-05
-func printCost(o *athena.GetQueryExecutionOutput) {
-	if o == nil || o.QueryExecution == nil || o.QueryExecution.Statistics == nil {
-		println("query cost: 0.0 USD")
-		return
+func CostUSD(scannedBytes int64) float64 {
+	return costUSD(scannedBytes, DefaultPricePerTB)
+}
+
+// costUSD is the same as CostUSD, but at pricePerTB.
+func costUSD(scannedBytes int64, pricePerTB float64) float64 {
+	if scannedBytes <= 0 {
+		return 0
+	}
+	if scannedBytes < MinBytesBilled {
+		scannedBytes = MinBytesBilled
 	}
-	dataScannedBytes := o.QueryExecution.Statistics.DataScannedInBytes
-	if dataScannedBytes == nil {
-		println("query cost: 0.0 USD")
-	} else if *dataScannedBytes < 10*1024*1024 {
-		println("query cost: 0.0000476837158203125 USD")
-	} else {
-		fmt.Printf("query cost: %.20f USD\n", float64(*dataScannedBytes)/1024.0/1024.0*0.00000476837158203125)
+	return float64(scannedBytes) / (1 << 40) * pricePerTB
+}
+
+// printCost is to print query cost at pricePerTB with Logger.
+func printCost(logger Logger, o *athena.GetQueryExecutionOutput, pricePerTB float64) {
+	if o == nil || o.QueryExecution == nil || o.QueryExecution.Statistics == nil ||
+		o.QueryExecution.Statistics.DataScannedInBytes == nil {
+		logger.Infof("query cost: 0.0 USD")
+		return
 	}
+	cost := costUSD(*o.QueryExecution.Statistics.DataScannedInBytes, pricePerTB)
+	logger.Infof("query cost: %s USD", strconv.FormatFloat(cost, 'f', -1, 64))
 }
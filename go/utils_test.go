@@ -21,11 +21,17 @@
 package athenadriver
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/aws/aws-sdk-go/service/athena"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
 	"math"
 	"os"
 	"strconv"
@@ -66,6 +72,56 @@ func TestRowsToCSV(t *testing.T) {
 	assert.Equal(t, "", s)
 }
 
+func TestRowsToMaps(t *testing.T) {
+	sqlRows := sqlmock.NewRows([]string{"id", "name", "ok", "score", "id"})
+	sqlRows.AddRow(int64(1), "a", true, 1.5, int64(2))
+	sqlRows.AddRow(int64(3), nil, false, nil, nil)
+	maps, err := RowsToMaps(mockRowsToSQLRows(sqlRows))
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"id": int64(1), "name": "a", "ok": true, "score": 1.5, "id_1": int64(2)},
+		{"id": int64(3), "name": nil, "ok": false, "score": nil, "id_1": nil},
+	}, maps)
+
+	sqlRows = sqlmock.NewRows([]string{"a"})
+	maps, err = RowsToMaps(mockRowsToSQLRows(sqlRows))
+	assert.Nil(t, err)
+	assert.Len(t, maps, 0)
+
+	sqlRows = sqlmock.NewRows([]string{"a"}).AddRow(1).RowError(0, ErrTestMockGeneric)
+	_, err = RowsToMaps(mockRowsToSQLRows(sqlRows))
+	assert.Equal(t, ErrTestMockGeneric, err)
+
+	maps, err = RowsToMaps(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, maps)
+
+	// json columns are embedded in JSON as they are
+	c := createConnectionFixture()
+	c.connector.config.SetMissingAsEmptyString(false)
+	c.connector.config.SetMissingAsNil(true)
+	db := sql.OpenDB(fixtureConnector{conn: c})
+	defer db.Close()
+	rows, err := db.Query("SELECT_JSON")
+	assert.Nil(t, err)
+	maps, err = RowsToMaps(rows)
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"id": int64(1), "doc": json.RawMessage(`{"a":[1,"x"]}`)},
+		{"id": int64(2), "doc": nil},
+	}, maps)
+	b, err := json.Marshal(maps)
+	assert.Nil(t, err)
+	assert.Equal(t, `[{"doc":{"a":[1,"x"]},"id":1},{"doc":null,"id":2}]`, string(b))
+}
+
+func TestUniqueColumnNames(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, uniqueColumnNames([]string{"a", "b"}))
+	assert.Equal(t, []string{"a", "a_1", "a_2"}, uniqueColumnNames([]string{"a", "a", "a"}))
+	assert.Equal(t, []string{"a", "a_2", "a_1", "_col0"}, uniqueColumnNames([]string{"a", "a", "a_1", "_col0"}))
+	assert.Equal(t, []string{"a", "a_1", "a_1_1"}, uniqueColumnNames([]string{"a", "a_1", "a_1"}))
+}
+
 func TestColsRowsToCSV(t *testing.T) {
 	sqlRows := sqlmock.NewRows([]string{"one", "two", "three"})
 	sqlRows.AddRow("1", "2", "3")
@@ -74,6 +130,189 @@ func TestColsRowsToCSV(t *testing.T) {
 	assert.Equal(t, expected, "one,two,three\n1,2,3\n")
 }
 
+func TestRowsToCSVWriter(t *testing.T) {
+	value := "a,\"b\"\nc"
+	sqlRows := sqlmock.NewRows([]string{"one", "two,2", "three"})
+	sqlRows.AddRow(value, "2", nil)
+	rows := mockRowsToSQLRows(sqlRows)
+	var buf bytes.Buffer
+	err := RowsToCSVWriter(rows, &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "one,\"two,2\",three\n\"a,\"\"b\"\"\nc\",2,\n", buf.String())
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"one", "two,2", "three"}, {value, "2", ""}}, records)
+
+	// tab delimited without header
+	sqlRows = sqlmock.NewRows([]string{"one", "two"})
+	sqlRows.AddRow(value, "2")
+	rows = mockRowsToSQLRows(sqlRows)
+	buf.Reset()
+	err = RowsToCSVWriterWithOptions(rows, &buf, CSVOptions{Delimiter: '\t', OmitHeader: true})
+	assert.Nil(t, err)
+	reader := csv.NewReader(&buf)
+	reader.Comma = '\t'
+	records, err = reader.ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{value, "2"}}, records)
+
+	// invalid delimiter
+	sqlRows = sqlmock.NewRows([]string{"one"})
+	rows = mockRowsToSQLRows(sqlRows)
+	err = RowsToCSVWriterWithOptions(rows, &buf, CSVOptions{Delimiter: '"'})
+	assert.NotNil(t, err)
+
+	assert.Nil(t, RowsToCSVWriter(nil, &buf))
+}
+
+func TestWriteCSV(t *testing.T) {
+	newRows := func() *sql.Rows {
+		sqlRows := sqlmock.NewRows([]string{"one", "two"})
+		sqlRows.AddRow("a;b", nil)
+		sqlRows.AddRow("c", "2")
+		return mockRowsToSQLRows(sqlRows)
+	}
+	var buf bytes.Buffer
+	assert.Nil(t, WriteCSV(newRows(), &buf))
+	assert.Equal(t, "one,two\na;b,\nc,2\n", buf.String())
+
+	buf.Reset()
+	assert.Nil(t, WriteCSV(newRows(), &buf, CSVHeader(false), CSVDelimiter(';'), CSVCRLF(true)))
+	assert.Equal(t, "\"a;b\";\r\nc;2\r\n", buf.String())
+
+	buf.Reset()
+	assert.Nil(t, WriteCSV(newRows(), &buf, CSVHeader(false), CSVHeader(true), CSVGzip(true)))
+	gzipReader, err := gzip.NewReader(&buf)
+	assert.Nil(t, err)
+	b, err := ioutil.ReadAll(gzipReader)
+	assert.Nil(t, err)
+	assert.Equal(t, "one,two\na;b,\nc,2\n", string(b))
+
+	// the wrappers
+	assert.Equal(t, "one,two\na;b,\nc,2\n", ColsRowsToCSV(newRows()))
+	assert.Equal(t, "a;b,\nc,2\n", RowsToCSV(newRows()))
+	assert.Equal(t, "one,two\n", ColsToCSV(newRows()))
+	assert.Equal(t, "", ColsRowsToCSV(nil))
+}
+
+// recordingWriter records the size of each write.
+type recordingWriter struct {
+	sizes []int
+	total int
+}
+
+func (r *recordingWriter) Write(p []byte) (int, error) {
+	r.sizes = append(r.sizes, len(p))
+	r.total += len(p)
+	return len(p), nil
+}
+
+func TestWriteCSVBounded(t *testing.T) {
+	newRows := func(n int) *sql.Rows {
+		sqlRows := sqlmock.NewRows([]string{"id", "name"})
+		for i := 0; i < n; i++ {
+			sqlRows.AddRow(strconv.Itoa(i), randString(20))
+		}
+		return mockRowsToSQLRows(sqlRows)
+	}
+	// every write is bounded by the buffer size, regardless of the result size
+	w := &recordingWriter{}
+	assert.Nil(t, WriteCSV(newRows(20000), w))
+	assert.True(t, w.total > 400000)
+	for _, size := range w.sizes {
+		assert.True(t, size <= 4096)
+	}
+
+	w = &recordingWriter{}
+	assert.Nil(t, WriteCSV(newRows(20000), w, CSVBufferSize(64<<10)))
+	assert.True(t, len(w.sizes) > 1)
+	for _, size := range w.sizes {
+		assert.True(t, size <= 64<<10)
+	}
+
+	// a smaller size keeps the default, and all the rows are written
+	var buf bytes.Buffer
+	assert.Nil(t, WriteCSV(newRows(1000), &buf, CSVBufferSize(16)))
+	records, err := csv.NewReader(&buf).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, 1001, len(records))
+	assert.Equal(t, "999", records[1000][0])
+	buf.Reset()
+	assert.Nil(t, WriteCSV(newRows(0), &buf, CSVBufferSize(16)))
+	assert.Equal(t, "id,name\n", buf.String())
+
+	// flushed every 2 rows, the last row is flushed at the end
+	w = &recordingWriter{}
+	assert.Nil(t, WriteCSV(newRows(5), w, CSVHeader(false), CSVFlushInterval(2)))
+	assert.Equal(t, 3, len(w.sizes))
+
+	err = WriteCSV(newRows(5), &failingWriter{}, CSVFlushInterval(1))
+	assert.Equal(t, ErrTestMockGeneric, err)
+}
+
+type failingWriter struct{}
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	return 0, ErrTestMockGeneric
+}
+
+func TestRowsToCSVWriterGzip(t *testing.T) {
+	value := "a,\"b\"\nc"
+	sqlRows := sqlmock.NewRows([]string{"one", "two"})
+	sqlRows.AddRow(value, "2")
+	rows := mockRowsToSQLRows(sqlRows)
+	var buf bytes.Buffer
+	err := RowsToCSVWriterWithOptions(rows, &buf, CSVOptions{Gzip: true})
+	assert.Nil(t, err)
+
+	gzipReader, err := gzip.NewReader(&buf)
+	assert.Nil(t, err)
+	records, err := csv.NewReader(gzipReader).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{{"one", "two"}, {value, "2"}}, records)
+
+	// gzip stream is closed on error
+	sqlRows = sqlmock.NewRows([]string{"one"})
+	rows = mockRowsToSQLRows(sqlRows)
+	buf.Reset()
+	err = RowsToCSVWriterWithOptions(rows, &buf, CSVOptions{Gzip: true, Delimiter: '"'})
+	assert.NotNil(t, err)
+	gzipReader, err = gzip.NewReader(&buf)
+	assert.Nil(t, err)
+	_, err = ioutil.ReadAll(gzipReader)
+	assert.Nil(t, err)
+
+	// error of closing gzip stream is returned
+	sqlRows = sqlmock.NewRows([]string{"one"})
+	rows = mockRowsToSQLRows(sqlRows)
+	err = RowsToCSVWriterWithOptions(rows, &failingWriter{}, CSVOptions{Gzip: true})
+	assert.Equal(t, ErrTestMockGeneric, err)
+}
+
+func TestRowsToCSVQuoting(t *testing.T) {
+	sqlRows := sqlmock.NewRows([]string{"one", "two"})
+	sqlRows.AddRow("a,b", "2")
+	rows := mockRowsToSQLRows(sqlRows)
+	assert.Equal(t, "\"a,b\",2\n", RowsToCSV(rows))
+
+	sqlRows = sqlmock.NewRows([]string{"one,1", "two"})
+	rows = mockRowsToSQLRows(sqlRows)
+	assert.Equal(t, "\"one,1\",two\n", ColsToCSV(rows))
+}
+
+func TestParseS3URI(t *testing.T) {
+	bucket, key, ok := parseS3URI("s3://query-results/prefix/abc.csv")
+	assert.True(t, ok)
+	assert.Equal(t, "query-results", bucket)
+	assert.Equal(t, "prefix/abc.csv", key)
+
+	for _, uri := range []string{"", "s3://", "s3://bucket", "s3://bucket/", "s3:///key", "https://bucket/key"} {
+		_, _, ok = parseS3URI(uri)
+		assert.False(t, ok, uri)
+	}
+}
+
 func TestIsSelectStatement(t *testing.T) {
 	assert.True(t, colInFirstPage("SELECT"))
 	assert.True(t, colInFirstPage(" SELECT"))
@@ -86,6 +325,150 @@ func TestIsInsetStatement(t *testing.T) {
 	assert.True(t, isInsertStatement("insert"))
 }
 
+func TestStatementType(t *testing.T) {
+	tests := []struct {
+		query     string
+		queryType string
+		writeDML  bool
+	}{
+		{"SELECT 1", athena.StatementTypeDml, false},
+		{"WITH t AS (SELECT 1) SELECT * FROM t", athena.StatementTypeDml, false},
+		{"INSERT INTO t VALUES (1)", athena.StatementTypeDml, true},
+		{"MERGE INTO t USING s ON t.id = s.id WHEN MATCHED THEN DELETE", athena.StatementTypeDml, true},
+		{"  merge into t using s on t.id = s.id when not matched then insert values (s.id)",
+			athena.StatementTypeDml, true},
+		{"-- fix typo\nUPDATE t SET a = 1 WHERE id = 2", athena.StatementTypeDml, true},
+		{"/* gdpr */\n\tDELETE FROM t WHERE id = 2", athena.StatementTypeDml, true},
+		{"/* a */ -- b\n update t set a = 1", athena.StatementTypeDml, true},
+		{"DESCRIBE t", athena.StatementTypeUtility, false},
+		{"SHOW TABLES", athena.StatementTypeUtility, false},
+		{"EXPLAIN DELETE FROM t", athena.StatementTypeUtility, false},
+		{"CREATE TABLE t (a int)", athena.StatementTypeDdl, false},
+		{"MSCK REPAIR TABLE t", athena.StatementTypeDdl, false},
+		{"DELETED", athena.StatementTypeDdl, false},
+		{"-- nothing", "", false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.queryType, statementType(test.query), test.query)
+		assert.Equal(t, test.writeDML, isWriteDMLStatement(test.query), test.query)
+		assert.False(t, isReadOnlyStatement(test.query) && test.writeDML, test.query)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		script     string
+		statements []string
+	}{
+		{"SELECT 1", []string{"SELECT 1"}},
+		{"SELECT 1;", []string{"SELECT 1"}},
+		{"SELECT 1; SELECT 2;\n", []string{"SELECT 1", "SELECT 2"}},
+		{"SELECT ';'; SELECT 2", []string{"SELECT ';'", "SELECT 2"}},
+		{"SELECT 'it''s;'; SELECT \"a;b\" FROM t", []string{"SELECT 'it''s;'", "SELECT \"a;b\" FROM t"}},
+		{"SELECT 1 /* ; */; SELECT 2", []string{"SELECT 1 /* ; */", "SELECT 2"}},
+		{"SELECT 1 -- ;\n; SELECT 2", []string{"SELECT 1 -- ;", "SELECT 2"}},
+		{"-- header\nCREATE TABLE t (a int);\n\n-- load\nINSERT INTO t VALUES (1);\n-- done\n",
+			[]string{"-- header\nCREATE TABLE t (a int)", "-- load\nINSERT INTO t VALUES (1)"}},
+		{";;  ;", nil},
+		{"", nil},
+	}
+	for _, test := range tests {
+		statements, ok := splitStatements(test.script)
+		assert.True(t, ok, test.script)
+		assert.Equal(t, test.statements, statements, test.script)
+	}
+
+	for _, script := range []string{"SELECT 'a; SELECT 2", "SELECT 1; /* SELECT 2"} {
+		_, ok := splitStatements(script)
+		assert.False(t, ok, script)
+	}
+}
+
+func TestStatementClassifiers(t *testing.T) {
+	tests := []struct {
+		query    string
+		keyword  string
+		colFirst bool
+		readOnly bool
+		insert   bool
+		utility  bool
+	}{
+		{"SELECT 1", "select", true, true, false, false},
+		{"\n\t select 1", "select", true, true, false, false},
+		{"(SELECT 1) UNION (SELECT 2)", "select", true, true, false, false},
+		{"-- comment\nSELECT 1", "select", true, true, false, false},
+		{"-- comment with select\n-- another\n  SELECT 1", "select", true, true, false, false},
+		{"/* block */ SELECT 1", "select", true, true, false, false},
+		{"/* multi\nline */\n/* another */SELECT 1", "select", true, true, false, false},
+		{"/* nested -- line */ select 1", "select", true, true, false, false},
+		{"-- comment\n/* block */\nWITH t AS (SELECT 1) SELECT * FROM t", "with", true, true, false, false},
+		{"WITH t AS (SELECT 1) SELECT * FROM t", "with", true, true, false, false},
+		{"USING FUNCTION f(x INTEGER) RETURNS INTEGER TYPE LAMBDA_INVOKE WITH (lambda_name = 'l') SELECT f(1)",
+			"using", true, true, false, false},
+		{"VALUES 1, 2", "values", true, true, false, false},
+		{"INSERT INTO t VALUES (1)", "insert", false, false, true, false},
+		{"/* select */ INSERT INTO t SELECT 1", "insert", false, false, true, false},
+		{"-- select\ninsert into t select 1", "insert", false, false, true, false},
+		{"DESC t", "desc", false, true, false, true},
+		{"DESCRIBE t", "describe", false, true, false, true},
+		{"-- comment\nSHOW TABLES", "show", false, true, false, true},
+		{"/* plan */ EXPLAIN SELECT 1", "explain", false, true, false, true},
+		{"DESCRIPTION", "description", false, false, false, false},
+		{"SELECTED", "selected", false, false, false, false},
+		{"CREATE TABLE t AS SELECT 1", "create", false, false, false, false},
+		{"DROP TABLE t -- SELECT", "drop", false, false, false, false},
+		{"-- comment\rSELECT 1", "select", true, true, false, false},
+		{"EXPLAIN ANALYZE -- plan\rINSERT INTO t SELECT 1", "explain", false, false, false, true},
+		{"-- SELECT 1", "", false, false, false, false},
+		{"/* SELECT 1", "", false, false, false, false},
+		{"", "", false, false, false, false},
+		{"  ", "", false, false, false, false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.keyword, leadingKeyword(test.query), test.query)
+		assert.Equal(t, test.colFirst, colInFirstPage(test.query), test.query)
+		assert.Equal(t, test.readOnly, isReadOnlyStatement(test.query), test.query)
+		assert.Equal(t, test.insert, isInsertStatement(test.query), test.query)
+		assert.Equal(t, test.utility, isUtilityStatement(test.query), test.query)
+	}
+}
+
+func TestIsReadOnlyStatement(t *testing.T) {
+	for _, query := range []string{
+		"SELECT 1",
+		"(SELECT 1) UNION (SELECT 2)",
+		"WITH t AS (SELECT 1) SELECT * FROM t",
+		"-- INSERT\rSELECT 1",
+		"EXPLAIN INSERT INTO t SELECT 1",
+		"EXPLAIN (TYPE DISTRIBUTED) DROP TABLE t",
+		"EXPLAIN ANALYZE SELECT 1",
+		"explain analyze verbose /* x */ select 1",
+		"SHOW TABLES",
+		"VALUES 1, 2",
+		"(values (1, 'a'), (2, 'b'))",
+		"EXPLAIN ANALYZE VALUES 1",
+	} {
+		assert.True(t, isReadOnlyStatement(query), query)
+	}
+	for _, query := range []string{
+		"INSERT INTO t SELECT 1",
+		"UNLOAD (SELECT 1) TO 's3://bucket/' WITH (format = 'PARQUET')",
+		"-- SELECT 1\rDROP TABLE t",
+		"/* SELECT 1 */ DROP TABLE t",
+		"/*/ SELECT 1 */ CREATE TABLE t AS SELECT 1",
+		"EXPLAIN ANALYZE INSERT INTO t SELECT 1",
+		"EXPLAIN ANALYZE VERBOSE (DROP TABLE t)",
+		"EXPLAIN ANALYZE",
+		"MSCK REPAIR TABLE t",
+		"ANALYZE t",
+		"ANALYZE SELECT 1",
+		"-- SELECT 1",
+		"",
+	} {
+		assert.False(t, isReadOnlyStatement(query), query)
+	}
+}
+
 func TestRandInt8(t *testing.T) {
 	s := randInt8()
 	i, err := strconv.ParseInt(*s, 10, 8)
@@ -108,8 +491,8 @@ func TestRandInt(t *testing.T) {
 }
 
 func TestRandInt64(t *testing.T) {
-	s := randUInt64()
-	_, err := strconv.ParseUint(*s, 10, 64)
+	s := randInt64()
+	_, err := strconv.ParseInt(*s, 10, 64)
 	assert.Nil(t, err)
 }
 
@@ -175,14 +558,159 @@ func TestValueToNamedValue(t *testing.T) {
 }
 
 func TestIsQueryTimeOut(t *testing.T) {
-	assert.False(t, isQueryTimeOut(time.Now(), athena.StatementTypeDdl))
-	assert.False(t, isQueryTimeOut(time.Now(), athena.StatementTypeDml))
-	assert.False(t, isQueryTimeOut(time.Now(), athena.StatementTypeUtility))
+	config := NewNoOpsConfig()
+	assert.False(t, isQueryTimeOut(time.Now(), athena.StatementTypeDdl, config))
+	assert.False(t, isQueryTimeOut(time.Now(), athena.StatementTypeDml, config))
+	assert.False(t, isQueryTimeOut(time.Now(), athena.StatementTypeUtility, config))
 	now := time.Now()
 	OneHourAgo := now.Add(-3600 * time.Second)
-	assert.True(t, isQueryTimeOut(OneHourAgo, athena.StatementTypeDml))
-	assert.False(t, isQueryTimeOut(OneHourAgo, athena.StatementTypeDdl))
-	assert.False(t, isQueryTimeOut(OneHourAgo, "UNKNOWN"))
+	assert.True(t, isQueryTimeOut(OneHourAgo, athena.StatementTypeDml, config))
+	assert.False(t, isQueryTimeOut(OneHourAgo, athena.StatementTypeDdl, config))
+	assert.False(t, isQueryTimeOut(OneHourAgo, "UNKNOWN", config))
+
+	// long ETL queries
+	_ = config.SetDMLQueryTimeout(2 * time.Hour)
+	assert.False(t, isQueryTimeOut(OneHourAgo, athena.StatementTypeDml, config))
+	assert.False(t, isQueryTimeOut(OneHourAgo, athena.StatementTypeUtility, config))
+	_ = config.SetDDLQueryTimeout(30 * time.Minute)
+	assert.True(t, isQueryTimeOut(OneHourAgo, athena.StatementTypeDdl, config))
+	assert.True(t, isQueryTimeOut(OneHourAgo, "UNKNOWN", config))
+}
+
+func TestQueryDeadline(t *testing.T) {
+	config := NewNoOpsConfig()
+	now := time.Now()
+	deadline, ctxLimited := queryDeadline(context.Background(), now, athena.StatementTypeDml, config)
+	assert.False(t, ctxLimited)
+	assert.Equal(t, now.Add(DMLQueryTimeout*time.Second), deadline)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+	ctxDeadline, _ := ctx.Deadline()
+	deadline, ctxLimited = queryDeadline(ctx, now, athena.StatementTypeDml, config)
+	assert.True(t, ctxLimited)
+	assert.Equal(t, ctxDeadline, deadline)
+
+	deadline, ctxLimited = queryDeadline(ctx, now, "TIMEOUT_NOW", config)
+	assert.False(t, ctxLimited)
+	assert.Equal(t, now, deadline)
+
+	_ = config.SetDMLQueryTimeout(time.Second)
+	deadline, ctxLimited = queryDeadline(ctx, now, athena.StatementTypeDml, config)
+	assert.False(t, ctxLimited)
+	assert.Equal(t, now.Add(time.Second), deadline)
+}
+
+func TestGetClientRequestToken(t *testing.T) {
+	assert.Nil(t, getClientRequestToken(context.Background(), "SELECT 1"))
+
+	token := "0123456789abcdef0123456789abcdef"
+	ctx := context.WithValue(context.Background(), ClientRequestTokenKey, token)
+	ctx = context.WithValue(ctx, IdempotencyKey, "submission-1")
+	assert.Equal(t, token, *getClientRequestToken(ctx, "SELECT 1"))
+
+	ctx = context.WithValue(context.Background(), IdempotencyKey, "submission-1")
+	t1 := getClientRequestToken(ctx, "SELECT 1")
+	assert.Equal(t, 64, len(*t1))
+	assert.Equal(t, *t1, *getClientRequestToken(ctx, "SELECT 1"))
+	assert.NotEqual(t, *t1, *getClientRequestToken(ctx, "SELECT 2"))
+	ctx = context.WithValue(context.Background(), IdempotencyKey, "submission-2")
+	assert.NotEqual(t, *t1, *getClientRequestToken(ctx, "SELECT 1"))
+}
+
+func TestCountPlaceholders(t *testing.T) {
+	assert.Equal(t, 0, countPlaceholders("SELECT 1"))
+	assert.Equal(t, 2, countPlaceholders("SELECT * FROM t WHERE a = ? AND b = ?"))
+	assert.Equal(t, 1, countPlaceholders("SELECT 'what?' FROM t WHERE a = ?"))
+	assert.Equal(t, 1, countPlaceholders("SELECT 'what''s up?' FROM t WHERE a = ?"))
+	assert.Equal(t, 1, countPlaceholders(`SELECT "col?" FROM t WHERE a = ?`))
+	assert.Equal(t, 1, countPlaceholders("SELECT `col?` FROM t WHERE a = ?"))
+	assert.Equal(t, 1, countPlaceholders("SELECT a -- is it ?\nFROM t WHERE a = ?"))
+	assert.Equal(t, 1, countPlaceholders("SELECT a FROM t WHERE a = ? -- is it ?"))
+	assert.Equal(t, 1, countPlaceholders("SELECT a -- is it ?\rFROM t WHERE a = ?"))
+	assert.Equal(t, 1, countPlaceholders("SELECT /* is it ? */ a FROM t WHERE a = ?"))
+	assert.Equal(t, 1, countPlaceholders("SELECT a - 1 / 2 FROM t WHERE a = ?"))
+	assert.Equal(t, -1, countPlaceholders("SELECT 'what? FROM t WHERE a = ?"))
+	assert.Equal(t, -1, countPlaceholders("SELECT /* what? FROM t WHERE a = ?"))
+}
+
+func TestIsBytesScannedCutoff(t *testing.T) {
+	assert.True(t, isBytesScannedCutoff("Query cancelled! : Bytes scanned limit was exceeded"))
+	assert.False(t, isBytesScannedCutoff("something_broken"))
+	assert.False(t, isBytesScannedCutoff(""))
+}
+
+func TestIsUnsupportedInsertFormat(t *testing.T) {
+	assert.True(t, isUnsupportedInsertFormat("HIVE_UNSUPPORTED_FORMAT: Output format "+
+		"org.apache.hadoop.hive.ql.io.avro.AvroContainerOutputFormat with SerDe "+
+		"org.apache.hadoop.hive.serde2.avro.AvroSerDe is not supported."))
+	assert.True(t, isUnsupportedInsertFormat("NOT_SUPPORTED: Inserting into Hive table with SerDe "+
+		"org.openx.data.jsonserde.JsonSerDe is not supported"))
+	assert.False(t, isUnsupportedInsertFormat("SYNTAX_ERROR: line 1:8: Column 'x' cannot be resolved"))
+	assert.False(t, isUnsupportedInsertFormat("NOT_SUPPORTED: Correlated subquery is not supported"))
+	assert.False(t, isUnsupportedInsertFormat(""))
+}
+
+func TestSQLTokens(t *testing.T) {
+	query := "SELECT a1, 'it''s' -- c ?\r/* d */ FROM \"t\"?"
+	tokens, ok := sqlTokens(query)
+	assert.True(t, ok)
+	var texts []string
+	for _, token := range tokens {
+		texts = append(texts, query[token.start:token.end])
+	}
+	assert.Equal(t, []string{"SELECT", "a1", ",", "'it''s'", "FROM", `"t"`, "?"}, texts)
+	assert.Equal(t, []sqlTokenKind{sqlTokenWord, sqlTokenWord, sqlTokenSymbol, sqlTokenQuoted, sqlTokenWord,
+		sqlTokenQuoted, sqlTokenSymbol}, []sqlTokenKind{tokens[0].kind, tokens[1].kind, tokens[2].kind,
+		tokens[3].kind, tokens[4].kind, tokens[5].kind, tokens[6].kind})
+
+	tokens, ok = sqlTokens("SELECT 'a")
+	assert.False(t, ok)
+	assert.Len(t, tokens, 1)
+	_, ok = sqlTokens("SELECT /* a")
+	assert.False(t, ok)
+	tokens, ok = sqlTokens("SELECT -- a")
+	assert.True(t, ok)
+	assert.Len(t, tokens, 1)
+}
+
+func TestInsertTableName(t *testing.T) {
+	tests := map[string]string{
+		"INSERT INTO t VALUES (1)":                              "t",
+		"insert into db.t SELECT * FROM s":                      "db.t",
+		"INSERT INTO \"my db\" . \"t-1\" (a, b) VALUES (1, 2)":  "my db.t-1",
+		"-- load\n/* daily */ INSERT\n  INTO `db`.t2\nSELECT 1": "db.t2",
+		"INSERT INTO \"v1.2\".t SELECT 1":                       "v1.2.t",
+		"SELECT * FROM t":                                       "",
+		"INSERT OVERWRITE t SELECT 1":                           "",
+		"WITH s AS (SELECT 1) INSERT INTO t SELECT * FROM s":    "",
+		"-- load\rINSERT /* x */ INTO t SELECT 1":               "t",
+		"INSERT INTO \"a\"\"b\".t SELECT 1":                     "a\"b.t",
+		"INSERT INTO 't' SELECT 1":                              "",
+	}
+	for query, expected := range tests {
+		assert.Equal(t, expected, insertTableName(query), query)
+	}
+}
+
+func TestScannedString(t *testing.T) {
+	s, ok, err := scannedString([]byte("a"), "net.IP")
+	assert.Equal(t, "a", s)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	s, ok, err = scannedString("b", "net.IP")
+	assert.Equal(t, "b", s)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	_, ok, err = scannedString(nil, "net.IP")
+	assert.False(t, ok)
+	assert.Nil(t, err)
+
+	_, ok, err = scannedString(int64(1), "net.IP")
+	assert.False(t, ok)
+	assert.Equal(t, "cannot convert 1 (int64) to net.IP", err.Error())
 }
 
 func TestEscapeBytesBackslash(t *testing.T) {
@@ -234,12 +762,75 @@ func TestPrintCost(t *testing.T) {
 			},
 		},
 	}
-	printCost(nil)
-	printCost(o)
+	logger := &testLogger{}
+	printCost(logger, nil, DefaultPricePerTB)
+	printCost(logger, o, DefaultPricePerTB)
 	cost := int64(123)
 	o.QueryExecution.Statistics.DataScannedInBytes = &cost
-	printCost(o)
+	printCost(logger, o, DefaultPricePerTB)
 	cost = int64(12345678)
 	o.QueryExecution.Statistics.DataScannedInBytes = &cost
-	printCost(o)
+	printCost(logger, o, DefaultPricePerTB)
+	printCost(logger, o, 6.75)
+	assert.Equal(t, []string{
+		"query cost: 0.0 USD",
+		"query cost: 0.0 USD",
+		"query cost: 0.0000476837158203125 USD",
+		"query cost: 0.000056141643653973006 USD",
+		"query cost: 0.00007579121893286356 USD",
+	}, logger.infos)
+}
+
+func TestErrorCategory(t *testing.T) {
+	assert.Equal(t, ErrorCategoryUser, errorCategory("SYNTAX_ERROR: line 1:8: Column 'x' cannot be resolved"))
+	assert.Equal(t, ErrorCategoryUser, errorCategory("line 1:8: mismatched input 'FORM'. Expecting: <EOF>"))
+	assert.Equal(t, ErrorCategoryUser, errorCategory("TABLE_NOT_FOUND: line 1:15: Table 'db.t' does not exist"))
+	assert.Equal(t, ErrorCategorySystem, errorCategory("GENERIC_INTERNAL_ERROR: Unable to create input format"))
+	assert.Equal(t, ErrorCategorySystem, errorCategory(" INTERNAL_ERROR : something"))
+	assert.Equal(t, ErrorCategoryOther, errorCategory("HIVE_CURSOR_ERROR: Please reduce your request rate."))
+	assert.Equal(t, ErrorCategoryOther, errorCategory("something_broken"))
+	assert.Equal(t, ErrorCategoryOther, errorCategory(""))
+}
+
+func TestCostUSD(t *testing.T) {
+	assert.Equal(t, 0.0, CostUSD(0))
+	assert.Equal(t, 4.76837158203125e-05, CostUSD(1))
+	assert.Equal(t, 4.76837158203125e-05, CostUSD(MinBytesBilled))
+	assert.Equal(t, 5.0, CostUSD(1<<40))
+	assert.Equal(t, 6.75, costUSD(1<<40, 6.75))
+}
+
+func TestIsOutputAccessDenied(t *testing.T) {
+	assert.True(t, isOutputAccessDenied("Access denied when writing output to url: s3://bucket/q.csv . "+
+		"Please ensure you are allowed to access the S3 bucket."))
+	assert.False(t, isOutputAccessDenied("HIVE_CANNOT_OPEN_SPLIT: Error opening Hive split s3://data/f.parquet: "+
+		"Access Denied (Service: Amazon S3; Status Code: 403; Error Code: AccessDenied)"))
+	assert.False(t, isOutputAccessDenied("something_broken"))
+}
+
+func TestRetryClientRequestToken(t *testing.T) {
+	assert.Nil(t, retryClientRequestToken(nil, 1))
+	token := "token"
+	assert.Len(t, *retryClientRequestToken(&token, 1), 64)
+	assert.Equal(t, *retryClientRequestToken(&token, 1), *retryClientRequestToken(&token, 1))
+	assert.NotEqual(t, *retryClientRequestToken(&token, 1), *retryClientRequestToken(&token, 2))
+}
+
+func TestUpdateType(t *testing.T) {
+	tests := map[string]string{
+		"INSERT INTO t VALUES (1)":                          "INSERT",
+		"-- daily\ninsert into t select * from s":           "INSERT",
+		"UPDATE t SET a = 1":                                "UPDATE",
+		"DELETE FROM t WHERE a = 1":                         "DELETE",
+		"MERGE INTO t USING s ON t.id = s.id":               "MERGE",
+		"CREATE TABLE t WITH (format = 'ORC') AS SELECT 1":  "CREATE TABLE AS SELECT",
+		"CREATE TABLE t (a int)":                            "",
+		"CREATE EXTERNAL TABLE t (a int) LOCATION 's3://b'": "",
+		"CREATE VIEW v AS SELECT 1":                         "",
+		"SELECT 'INSERT'":                                   "",
+		"":                                                  "",
+	}
+	for query, expected := range tests {
+		assert.Equal(t, expected, updateType(query), query)
+	}
 }
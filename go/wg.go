@@ -22,6 +22,11 @@ package athenadriver
 
 import (
 	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/athena"
 	"github.com/aws/aws-sdk-go/service/athena/athenaiface"
@@ -60,6 +65,15 @@ func NewWG(name string, config *athena.WorkGroupConfiguration, tags *WGTags) *Wo
 	}
 }
 
+// reWGName is the pattern of a valid workgroup name.
+// https://docs.aws.amazon.com/athena/latest/APIReference/API_CreateWorkGroup.html
+var reWGName = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,128}$`)
+
+// isValidWGName is to check if name is a valid workgroup name.
+func isValidWGName(name string) bool {
+	return reWGName.MatchString(name)
+}
+
 // getWG is to get Athena Workgroup from AWS remotely.
 func getWG(ctx context.Context, athenaService athenaiface.AthenaAPI, Name string) (*athena.WorkGroup, error) {
 	if athenaService == nil {
@@ -75,6 +89,107 @@ func getWG(ctx context.Context, athenaService athenaiface.AthenaAPI, Name string
 	return getWorkGroupOutput.WorkGroup, nil
 }
 
+// workgroupCacheTTL is how long a workgroup got from Athena is cached in the connector, so a workgroup disabled,
+// deleted or reconfigured later is noticed in time.
+const workgroupCacheTTL = 5 * time.Minute
+
+// cachedWorkgroup is a workgroup cached in the connector, and when it expires.
+type cachedWorkgroup struct {
+	workgroup *athena.WorkGroup
+	expiry    time.Time
+}
+
+// getWorkgroup is to get Athena Workgroup from AWS remotely, which is cached in the connector for
+// workgroupCacheTTL once it is found enabled, so it is not got for every query.
+func (c *Connection) getWorkgroup(ctx context.Context, name string) (*athena.WorkGroup, error) {
+	if w, ok := c.connector.workgroups.Load(name); ok {
+		if cached := w.(cachedWorkgroup); time.Now().Before(cached.expiry) {
+			return cached.workgroup, nil
+		}
+		c.connector.workgroups.Delete(name)
+	}
+	w, err := getWG(ctx, c.athenaAPI, name)
+	if err != nil {
+		return nil, err
+	}
+	if aws.StringValue(w.State) == athena.WorkGroupStateEnabled {
+		c.connector.workgroups.Store(name, cachedWorkgroup{workgroup: w, expiry: time.Now().Add(workgroupCacheTTL)})
+	}
+	return w, nil
+}
+
+// withEngineVersion is to get a copy of config with SelectedEngineVersion set to version, or config itself if
+// version is empty.
+func withEngineVersion(config *athena.WorkGroupConfiguration, version string) *athena.WorkGroupConfiguration {
+	if version == "" {
+		return config
+	}
+	var c athena.WorkGroupConfiguration
+	if config != nil {
+		c = *config
+	}
+	c.EngineVersion = &athena.EngineVersion{SelectedEngineVersion: aws.String(version)}
+	return &c
+}
+
+// checkEngineVersion is to check if the effective engine version of Athena workgroup w, named name, is version.
+// Any engine version is accepted if version is empty or AUTO.
+func checkEngineVersion(name string, w *athena.WorkGroup, version string) error {
+	if version == "" || strings.EqualFold(version, "AUTO") {
+		return nil
+	}
+	var effective string
+	if w.Configuration != nil && w.Configuration.EngineVersion != nil {
+		effective = aws.StringValue(w.Configuration.EngineVersion.EffectiveEngineVersion)
+	}
+	if !strings.EqualFold(effective, version) {
+		return fmt.Errorf("%w: workgroup %q runs %q instead of %q", ErrEngineVersionMismatch, name, effective,
+			version)
+	}
+	return nil
+}
+
+// resultConfiguration is to get the ResultConfiguration of StartQueryExecution in workgroup wgName, whose
+// settings got from Athena are athenaWG, or nil if unknown.
+// The OutputLocation in DSN is not sent if the workgroup enforces its own configuration, as Athena would
+// reject it. An error is returned if neither DSN nor workgroup has an output location.
+func (c *Connection) resultConfiguration(ctx context.Context, wgName string,
+	athenaWG *athena.WorkGroup) (*athena.ResultConfiguration, error) {
+	hasOutputBucket := c.connector.config.hasOutputBucket()
+	if athenaWG == nil && !hasOutputBucket {
+		// the workgroup may still provide the output location
+		athenaWG, _ = c.getWorkgroup(ctx, wgName)
+	}
+	var wgOutputLocation string
+	if athenaWG != nil && athenaWG.Configuration != nil {
+		if aws.BoolValue(athenaWG.Configuration.EnforceWorkGroupConfiguration) {
+			return nil, nil
+		}
+		if athenaWG.Configuration.ResultConfiguration != nil {
+			wgOutputLocation = aws.StringValue(athenaWG.Configuration.ResultConfiguration.OutputLocation)
+		}
+	}
+	var aclConfiguration *athena.AclConfiguration
+	if o := c.connector.config.GetAclOption(); o != "" {
+		aclConfiguration = &athena.AclConfiguration{S3AclOption: aws.String(o)}
+	}
+	if hasOutputBucket {
+		return &athena.ResultConfiguration{
+			OutputLocation:   aws.String(c.connector.config.GetOutputLocation(time.Now())),
+			AclConfiguration: aclConfiguration,
+		}, nil
+	}
+	if wgOutputLocation != "" || athenaWG == nil {
+		// leave the output location to Athena if the workgroup can't be got
+		if aclConfiguration != nil {
+			return &athena.ResultConfiguration{AclConfiguration: aclConfiguration}, nil
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("%w: set it in DSN like s3://bucket/prefix, or in the result configuration of workgroup %q",
+		ErrConfigOutputLocationNotFound, wgName)
+}
+
 // CreateWGRemotely is to create a Workgroup remotely.
 func (w *Workgroup) CreateWGRemotely(athenaService athenaiface.AthenaAPI) error {
 	tags := w.Tags.Get()
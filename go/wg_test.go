@@ -22,8 +22,12 @@ package athenadriver
 
 import (
 	"context"
-	"github.com/stretchr/testify/assert"
+	"errors"
 	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/athena"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestNewWG(t *testing.T) {
@@ -76,3 +80,35 @@ func TestWorkgroup_CreateWGRemotely2(t *testing.T) {
 	e = wg.CreateWGRemotely(athenaClient)
 	assert.Nil(t, e)
 }
+
+func TestIsValidWGName(t *testing.T) {
+	assert.True(t, isValidWGName(DefaultWGName))
+	assert.True(t, isValidWGName("henry_wu.batch-1"))
+	assert.False(t, isValidWGName(""))
+	assert.False(t, isValidWGName("henry wu"))
+	assert.False(t, isValidWGName(randString(129)))
+}
+
+func TestWithEngineVersion(t *testing.T) {
+	config := GetDefaultWGConfig()
+	assert.True(t, config == withEngineVersion(config, ""))
+
+	c := withEngineVersion(config, "AUTO")
+	assert.Equal(t, "AUTO", *c.EngineVersion.SelectedEngineVersion)
+	assert.Equal(t, config.BytesScannedCutoffPerQuery, c.BytesScannedCutoffPerQuery)
+	assert.Nil(t, config.EngineVersion)
+
+	c = withEngineVersion(nil, "Athena engine version 3")
+	assert.Equal(t, "Athena engine version 3", *c.EngineVersion.SelectedEngineVersion)
+}
+
+func TestCheckEngineVersion(t *testing.T) {
+	w := &athena.WorkGroup{Configuration: GetDefaultWGConfig()}
+	assert.Nil(t, checkEngineVersion("wg", w, ""))
+	assert.Nil(t, checkEngineVersion("wg", w, "auto"))
+	assert.True(t, errors.Is(checkEngineVersion("wg", w, "Athena engine version 3"), ErrEngineVersionMismatch))
+
+	w.Configuration.EngineVersion = &athena.EngineVersion{EffectiveEngineVersion: aws.String("Athena engine version 3")}
+	assert.Nil(t, checkEngineVersion("wg", w, "athena engine version 3"))
+	assert.True(t, errors.Is(checkEngineVersion("wg", w, "Athena engine version 2"), ErrEngineVersionMismatch))
+}